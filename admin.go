@@ -0,0 +1,215 @@
+package config
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kk-kwok/config/version"
+)
+
+// EnvAdminToken is the default token of WithAdminToken
+const EnvAdminToken = "CONFIG_ADMIN_TOKEN"
+
+// ReloadStatus is the outcome of the reloads of a ConfigLoader
+type ReloadStatus struct {
+	Source      string    `json:"source"`    // the providers of the current config
+	SHA256      string    `json:"sha256"`    // sha256 of the current config
+	LoadedAt    time.Time `json:"loaded_at"` // when the current config is loaded
+	Reloads     int       `json:"reloads"`
+	Failures    int       `json:"failures"`
+	LastAttempt time.Time `json:"last_attempt,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`   // error of the last reload, empty if it succeeded
+	LastChanges Changes   `json:"last_changes,omitempty"` // changes of the last successful reload
+}
+
+// reloadState record the reload outcome for ReloadStatus
+type reloadState struct {
+	mu          sync.Mutex
+	reloads     int
+	failures    int
+	lastAttempt time.Time
+	lastErr     error
+	lastChanges Changes
+}
+
+func (s *reloadState) record(changes Changes, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastAttempt = time.Now()
+	s.lastErr = err
+	if err != nil {
+		s.failures++
+	} else {
+		s.reloads++
+		s.lastChanges = changes
+	}
+}
+
+// ReloadStatus returns the current config and the outcome of the last reload
+func (cl *ConfigLoader) ReloadStatus() ReloadStatus {
+	var status ReloadStatus
+	if snap := cl.current(); snap != nil {
+		status.Source = snap.source
+		status.SHA256 = snap.hash
+		status.LoadedAt = snap.loadedAt
+	}
+	cl.reloadState.mu.Lock()
+	defer cl.reloadState.mu.Unlock()
+	status.Reloads = cl.reloadState.reloads
+	status.Failures = cl.reloadState.failures
+	status.LastAttempt = cl.reloadState.lastAttempt
+	status.LastChanges = cl.reloadState.lastChanges
+	if cl.reloadState.lastErr != nil {
+		status.LastError = cl.reloadState.lastErr.Error()
+	}
+	return status
+}
+
+// AdminHandler returns the handler for inspecting the loaded config, mount it with the prefix stripped, e.g.
+//
+//	mux.Handle("/admin/", http.StripPrefix("/admin", loader.AdminHandler()))
+//
+// the endpoints:
+//   - GET /config?format=toml|yaml|json|env the current config with secrets redacted
+//   - GET /provenance the source of each key in json
+//   - GET /version the version info
+//   - GET /reload the reload status in json, POST /reload reload the config from the providers
+//   - GET /history the previously active configs without values, the newest first
+//   - GET|PUT|DELETE /log/level the LevelController set by WithLevelController
+//
+// with WithAdminToken or env CONFIG_ADMIN_TOKEN set, requests without the bearer token are rejected with 401
+func (cl *ConfigLoader) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/config", cl.serveConfig)
+	mux.HandleFunc("/provenance", cl.serveProvenance)
+	mux.HandleFunc("/version", serveVersion)
+	mux.HandleFunc("/reload", cl.serveReload)
+	mux.HandleFunc("/history", cl.serveHistory)
+	if cl.options.levelController != nil {
+		mux.Handle("/log/level", cl.options.levelController)
+	}
+	token := valueOrEnv(cl.options.adminToken, EnvAdminToken)
+	if token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="config"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (cl *ConfigLoader) serveConfig(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	snap := cl.current()
+	if snap == nil {
+		http.Error(w, ErrNotLoaded.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	format := cl.dumpFormat
+	if f := r.URL.Query().Get("format"); f != "" {
+		var err error
+		if format, err = ParseDumpFormat(f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	redacted, err := snap.redact()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("redact config failed, err=%v", err), http.StatusInternalServerError)
+		return
+	}
+	text, err := MarshalIndent(redacted, format)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s marshal failed, err=%v", format, err), http.StatusInternalServerError)
+		return
+	}
+	if format == DumpFormatJSON {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Header().Set("X-Config-Source", snap.source)
+	w.Header().Set("X-Config-Sha256", snap.hash)
+	fmt.Fprintln(w, text)
+}
+
+func (cl *ConfigLoader) serveProvenance(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	provenance := cl.Provenance()
+	if provenance == nil {
+		http.Error(w, ErrNotLoaded.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, provenance)
+}
+
+func serveVersion(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, version.Print(version.ServiceName))
+}
+
+func (cl *ConfigLoader) serveReload(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, cl.ReloadStatus())
+		return
+	}
+	cl.options.logger.Infow("reload config requested via admin handler", "remote_addr", r.RemoteAddr)
+	err := cl.Reload()
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, cl.ReloadStatus())
+	case errors.Is(err, ErrNotLoaded):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		writeJSON(w, http.StatusInternalServerError, cl.ReloadStatus())
+	}
+}
+
+func (cl *ConfigLoader) serveHistory(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, cl.History())
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	for _, m := range methods {
+		w.Header().Add("Allow", m)
+	}
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,61 @@
+package config
+
+// changeWatcher is implemented by the providers reporting changes via ChangeListener, for WithReloadOnChange
+type changeWatcher interface {
+	addChangeListener(listener ChangeListener)
+}
+
+// chainListener call the listener set on the provider first, then the added one
+func chainListener(listener, added ChangeListener) ChangeListener {
+	if listener == nil {
+		return added
+	}
+	return func(namespace, group, dataID, data string) {
+		listener(namespace, group, dataID, data)
+		added(namespace, group, dataID, data)
+	}
+}
+
+// listenProviderChanges add a listener queueing a reload to each provider before it is read,
+// the changes reported while a reload is queued or running are coalesced into one reload
+func (cl *ConfigLoader) listenProviderChanges() {
+	if cl.changes != nil {
+		return
+	}
+	cl.changes = make(chan string, 1)
+	for _, source := range cl.sources {
+		watcher, ok := source.provider.(changeWatcher)
+		if !ok {
+			continue
+		}
+		name := source.name
+		watcher.addChangeListener(func(namespace, group, dataID, data string) {
+			select {
+			case cl.changes <- name:
+			default:
+			}
+		})
+	}
+}
+
+// reloadOnProviderChange run the queued reloads after Load
+func (cl *ConfigLoader) reloadOnProviderChange() {
+	stop := make(chan struct{})
+	cl.closers = append(cl.closers, func() {
+		close(stop)
+	})
+	cl.options.logger.Infow("reload config on provider change")
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case name := <-cl.changes:
+				cl.options.logger.Infow("reload config on provider change received", "provider", name)
+				// the error is logged and recorded in ReloadStatus
+				_ = cl.Reload()
+			}
+		}
+	}()
+}
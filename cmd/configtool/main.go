@@ -0,0 +1,329 @@
+// configtool is the companion command of the config package
+//
+//	configtool keygen                                       generate an AES-256 key for ENC[...] values
+//	configtool encrypt [--key-file f] [--doc] [value|file]  encrypt a value, or a whole document with --doc
+//	configtool decrypt [--key-file f] [file]                decrypt the ENC[...] values or document
+//	configtool sign-keygen [--out config]                   generate an ed25519 key pair config.pub and config.key
+//	configtool sign --key-file config.key file              write the minisign signature file.minisig
+//	configtool verify --pub-key config.pub file             verify file against file.minisig or file.sig
+//	configtool diff [--format toml|yaml|json] old new       print the changed keys with secrets redacted
+//
+// like diff(1), configtool diff exits 1 if the configs differ and 2 on error
+// the keys are read from --key-file, env CONFIG_ENCRYPTION_KEY_FILE and CONFIG_ENCRYPTION_KEY, the first key is used for encryption
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kk-kwok/config"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"keygen":  {"generate a base64 encoded AES-256 key", runKeygen},
+	"encrypt": {"encrypt a value (default) or a whole document (--doc) from arg, file or stdin", runEncrypt},
+	"decrypt": {"decrypt the ENC[...] values or the whole document from file or stdin", runDecrypt},
+
+	"sign-keygen": {"generate an ed25519 key pair for signing config, the public key is minisign compatible", runSignKeygen},
+	"sign":        {"sign a config file, writes the detached minisign signature next to it", runSign},
+	"verify":      {"verify the detached signature of a config file", runVerify},
+
+	"diff": {"compare two config files key by key, exits 1 if they differ and 2 on error", runDiff},
+}
+
+// exitError set the exit code of the error returned by a command, the default is 1
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// nolint: forbidigo
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		code := 1
+		var exit *exitError
+		if errors.As(err, &exit) {
+			code = exit.code
+		}
+		// the changes are the output of diff
+		if !errors.Is(err, errConfigsDiffer) {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+		}
+		os.Exit(code)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [options]\n\nCommands:\n", os.Args[0])
+	for _, name := range sortedCommands() {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].usage)
+	}
+}
+
+func sortedCommands() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newFlagSet(name string) *pflag.FlagSet {
+	return pflag.NewFlagSet(name, pflag.ExitOnError)
+}
+
+// readInput read the file named by the first arg, or stdin if no arg or "-"
+func readInput(args []string) ([]byte, error) {
+	if len(args) == 0 || args[0] == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(args[0])
+}
+
+func runKeygen(args []string) error {
+	flags := newFlagSet("keygen")
+	_ = flags.Parse(args)
+	key, err := config.GenerateEncryptionKey()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, key)
+	return nil
+}
+
+func runEncrypt(args []string) error {
+	flags := newFlagSet("encrypt")
+	keyFile := flags.String("key-file", "", "encryption key file")
+	doc := flags.Bool("doc", false, "encrypt the whole document read from file or stdin")
+	_ = flags.Parse(args)
+
+	keys, err := config.LoadEncryptionKeys(*keyFile)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no encryption key, set --key-file, %v or %v", config.EnvEncryptionKeyFile, config.EnvEncryptionKey)
+	}
+
+	var plaintext []byte
+	typ := config.EncryptedTypeString
+	if *doc {
+		typ = config.EncryptedTypeDocument
+		plaintext, err = readInput(flags.Args())
+	} else if flags.NArg() > 0 {
+		plaintext = []byte(strings.Join(flags.Args(), " "))
+	} else {
+		plaintext, err = io.ReadAll(os.Stdin)
+		plaintext = []byte(strings.TrimRight(string(plaintext), "\r\n"))
+	}
+	if err != nil {
+		return err
+	}
+
+	text, err := config.EncryptValue(plaintext, keys[0], typ)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, text)
+	return nil
+}
+
+func runDecrypt(args []string) error {
+	flags := newFlagSet("decrypt")
+	keyFile := flags.String("key-file", "", "encryption key file")
+	_ = flags.Parse(args)
+
+	keys, err := config.LoadEncryptionKeys(*keyFile)
+	if err != nil {
+		return err
+	}
+	content, err := readInput(flags.Args())
+	if err != nil {
+		return err
+	}
+	plaintext, err := config.DecryptValues(content, keys)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(plaintext)
+	return err
+}
+
+func runSignKeygen(args []string) error {
+	flags := newFlagSet("sign-keygen")
+	out := flags.String("out", "config", "output path prefix, writes <out>.pub and <out>.key")
+	_ = flags.Parse(args)
+
+	pub, priv, err := config.GenerateSigningKey()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out+".key", []byte(priv), 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out+".pub", []byte(pub), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "private key: %s.key\npublic key:  %s.pub\n", *out, *out)
+	return nil
+}
+
+func runSign(args []string) error {
+	flags := newFlagSet("sign")
+	keyFile := flags.String("key-file", "", "private key file from sign-keygen")
+	comment := flags.String("comment", "", "trusted comment, default is the file name and timestamp")
+	output := flags.String("output", "", "signature output path, default <file>.minisig, - for stdout")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 || *keyFile == "" {
+		return fmt.Errorf("usage: sign --key-file config.key <file>")
+	}
+
+	file := flags.Arg(0)
+	priv, err := os.ReadFile(*keyFile)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if *comment == "" {
+		*comment = fmt.Sprintf("timestamp:%d\tfile:%s", time.Now().Unix(), filepath.Base(file))
+	}
+	sig, err := config.SignConfig(content, string(priv), *comment)
+	if err != nil {
+		return err
+	}
+	switch *output {
+	case "-":
+		_, err = os.Stdout.Write(sig)
+		return err
+	case "":
+		*output = file + ".minisig"
+	}
+	return os.WriteFile(*output, sig, 0o644)
+}
+
+func runVerify(args []string) error {
+	flags := newFlagSet("verify")
+	pubKeys := flags.StringArray("pub-key", nil, "trusted public key file, can be repeated")
+	sigFile := flags.String("sig", "", "signature file, default <file>.minisig or <file>.sig")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: verify --pub-key config.pub <file>")
+	}
+
+	var keys []string
+	for _, path := range *pubKeys {
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, string(key))
+	}
+	trusted, err := config.ParseTrustedKeys(keys)
+	if err != nil {
+		return err
+	}
+
+	file := flags.Arg(0)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	candidates := []string{*sigFile}
+	if *sigFile == "" {
+		candidates = []string{file + ".minisig", file + ".sig"}
+	}
+	var sig []byte
+	for _, path := range candidates {
+		if sig, err = os.ReadFile(path); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := config.VerifySignature(content, sig, trusted); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "signature verified")
+	return nil
+}
+
+var errConfigsDiffer = errors.New("configs differ")
+
+func runDiff(args []string) error {
+	changes, err := diffFiles(args)
+	if err != nil {
+		return &exitError{code: 2, err: err}
+	}
+	for _, line := range changes.Strings() {
+		fmt.Fprintln(os.Stdout, line)
+	}
+	if len(changes) > 0 {
+		return &exitError{code: 1, err: errConfigsDiffer}
+	}
+	return nil
+}
+
+func diffFiles(args []string) (config.Changes, error) {
+	flags := newFlagSet("diff")
+	format := flags.String("format", "", "format of the files, one of toml|yaml|json (default by file extension)")
+	_ = flags.Parse(args)
+	if flags.NArg() != 2 {
+		return nil, fmt.Errorf("usage: diff [--format toml|yaml|json] <old> <new>")
+	}
+
+	oldFile, newFile := flags.Arg(0), flags.Arg(1)
+	old, err := os.ReadFile(oldFile)
+	if err != nil {
+		return nil, err
+	}
+	new, err := os.ReadFile(newFile)
+	if err != nil {
+		return nil, err
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(oldFile), ".")
+	}
+	var unmarshaler config.Unmarshaler
+	switch strings.ToLower(*format) {
+	case "json":
+		unmarshaler = json.Unmarshal
+	case "yaml", "yml":
+		unmarshaler = yaml.Unmarshal
+	default:
+		unmarshaler = config.TomlUnmarshaler
+	}
+
+	return config.DiffDocuments(old, new, unmarshaler)
+}
@@ -1,15 +1,22 @@
 package config
 
 import (
+    "context"
     "errors"
     "fmt"
+    "net/url"
     "os"
     "reflect"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
 
     "go.uber.org/zap"
 
     "github.com/kk-kwok/config/version"
 
+    "github.com/prometheus/client_golang/prometheus"
     "github.com/spf13/pflag"
 )
 
@@ -24,14 +31,34 @@ type Base struct {
     MetricProcess bool `toml:"metric_process" yaml:"metric_process" json:"metric_process"` // prometheus process metrics
 
     OtlpGrpcEndpoint string `toml:"otlp_grpc_endpoint" yaml:"otlp_grpc_endpoint" json:"otlp_grpc_endpoint"`
+    // AdminAddr listen address like :9100 of /metrics and /debug/pprof/ served by Setup, empty disables the listener
+    AdminAddr string `toml:"admin_addr" yaml:"admin_addr" json:"admin_addr"`
 
     Log LogConfig `toml:"log" yaml:"log" json:"log"`
+
+    activeProfile string
+    registry      *prometheus.Registry
 }
 
 type BaseConfigEmbedded interface {
     InitOtlpGrpcEndpointFromEnv()
 }
 
+// profileSetter is implemented by Base, config types implementing BaseConfigEmbedded without embedding Base
+// don't expose the active profile
+type profileSetter interface {
+    setActiveProfile(profile string)
+}
+
+// ActiveProfile returns the profile the config is loaded with, e.g. dev, staging or prod, empty if none
+func (b *Base) ActiveProfile() string {
+    return b.activeProfile
+}
+
+func (b *Base) setActiveProfile(profile string) {
+    b.activeProfile = profile
+}
+
 func (b *Base) InitOtlpGrpcEndpointFromEnv() {
     if b.OtlpGrpcEndpoint != "" {
         return
@@ -53,6 +80,23 @@ type FlagParseResult interface {
     Usage() func()
 }
 
+// DumpFormatFlagResult is an optional interface of FlagParseResult, for custom flag parser to select the dump format
+type DumpFormatFlagResult interface {
+    DumpFormat() string
+}
+
+// ConfigSourcesFlagResult is an optional interface of FlagParseResult, for custom flag parser to pass
+// multiple config sources like file://, nacos://, http(s)://, env:// or - for stdin, see ProviderFromURI
+type ConfigSourcesFlagResult interface {
+    ConfigSources() []string
+}
+
+// DumpModeFlagResult is an optional interface of FlagParseResult, for custom flag parser to select the dump mode
+type DumpModeFlagResult interface {
+    DumpMode() string
+    DumpProvenance() bool
+}
+
 //
 // func (b *Base) SentryConfig() *SentryConfig {
 // 	return &b.Sentry
@@ -72,6 +116,10 @@ const (
 
     FlagConfigFile = "config"
     FlagDumpConfig = "dump"
+    FlagDumpFormat = "dump-format"
+    FlagDumpMode   = "dump-mode"
+    // FlagDumpProvenance annotate each key with its source in effective dump mode
+    FlagDumpProvenance = "dump-provenance"
 
     DefaultLogLevel = "info"
     // DefaultLogLevelNacos oops, nacos logging debug log as info level
@@ -83,6 +131,35 @@ const (
 type ConfigLoader struct {
     options    options
     configFile string
+    // sources are the providers used by Load, the configured providers or the ones created from --config uris
+    sources    []configSource
+    merge      bool
+    profile    string
+    dumpFormat DumpFormat
+    dumpMode   DumpMode
+
+    dumpProvenance bool
+
+    // defaults is a copy of the config passed to Load, snapshot holds the *snapshot of the current config
+    defaults   interface{}
+    snapshot   atomic.Value
+    reloadMu   sync.Mutex
+    listenerMu sync.Mutex
+    listeners  []ReloadListener
+
+    healthChecks []ReloadHealthCheck
+    historyMu    sync.Mutex
+    history      []*snapshot
+
+    reloadState reloadState
+    metrics     *loaderMetrics
+    closers     []func()
+    closeOnce   sync.Once
+    // changes queue a reload on provider change, see WithReloadOnChange
+    changes chan string
+
+    encryptionKeys [][]byte
+    trustedKeys    []*PublicKey
 }
 
 func New(opts ...Option) *ConfigLoader {
@@ -108,14 +185,14 @@ func New(opts ...Option) *ConfigLoader {
     }
 }
 
+// Load load the config into cfg, it returns ErrConfigDumped after dumping the config with --dump
 func (cl *ConfigLoader) Load(cfg interface{}) error {
     mtype := reflect.TypeOf(cfg)
     if mtype.Kind() != reflect.Ptr {
         return errors.New("only a pointer to struct or map can be unmarshalled from config content")
     }
 
-    baseEmbeded, ok := cfg.(BaseConfigEmbedded)
-    if !ok {
+    if _, ok := cfg.(BaseConfigEmbedded); !ok {
         return errors.New("error no embedded Base struct found. did your forget to embed the `infraconfig.Base` struct to your own config struct")
     }
 
@@ -126,7 +203,14 @@ func (cl *ConfigLoader) Load(cfg interface{}) error {
         flagResult = cl.defaultFlagParser()
     }
 
-    cl.configFile = flagResult.ConfigFile()
+    if err := cl.resolveConfigSources(flagResult); err != nil {
+        return err
+    }
+    cl.resolveProfile(flagResult)
+
+    if err := cl.resolveDumpOptions(flagResult); err != nil {
+        return err
+    }
 
     if flagResult.ShowHelp() {
         flagResult.Usage()()
@@ -160,6 +244,9 @@ func (cl *ConfigLoader) Load(cfg interface{}) error {
     // init unmarshaler
     if cl.options.unmarshaler == nil {
         cl.options.unmarshaler = TomlUnmarshaler
+        if cl.options.marshaler == nil {
+            cl.options.marshaler = TomlMarshaler
+        }
         cl.options.logger.Infow("using default TOML unmarshaler")
     } else {
         cl.options.logger.Infow("using custom unmarshaler")
@@ -167,35 +254,70 @@ func (cl *ConfigLoader) Load(cfg interface{}) error {
 
     isDump := os.Getenv("XXX_DUMP_DEMO_CFG") != "" || flagResult.DumpConfig()
 
-    content, err := cl.getConfigViaProviders()
+    // the demo template only contains the default values, providers are not consulted
+    if isDump && (cl.dumpMode == DumpModeDemo || os.Getenv("XXX_DUMP_DEMO_CFG") != "") {
+        cl.dumpMode = DumpModeDemo
+        cl.options.logger.Infow("begin dump demo config")
+        if err := cl.dumpConfig(cfg); err != nil {
+            return err
+        }
+        return ErrConfigDumped
+    }
 
-    if err != nil && !isDump {
+    keys, err := LoadEncryptionKeys(cl.options.encryptionKeyFile)
+    if err != nil {
         return err
     }
+    cl.encryptionKeys = keys
 
-    err = cl.options.unmarshaler(content, cfg)
+    cl.trustedKeys, err = ParseTrustedKeys(cl.options.trustedKeys)
     if err != nil {
-        return fmt.Errorf("unmarshal config failed, err=%w", err)
+        return fmt.Errorf("parse trusted keys failed, err=%w", err)
     }
 
-    baseEmbeded.InitOtlpGrpcEndpointFromEnv()
+    if cl.options.metricsRegisterer != nil && cl.metrics == nil {
+        cl.metrics, err = newLoaderMetrics(cl.options.metricsRegisterer)
+        if err != nil {
+            return err
+        }
+    }
 
-    cl.options.logger.Infow("config loaded successfully", "config", cfg)
+    if cl.profile != "" {
+        cl.options.logger.Infow("config profile active", "profile", cl.profile)
+    }
+    if _, err := reloadPolicies(cfg); err != nil {
+        return err
+    }
 
-    if cl.options.beforeInspectHook != nil {
-        cl.options.beforeInspectHook(cfg)
+    // the defaults are kept for Reload, which starts from them instead of the current config
+    cl.defaults, err = cloneConfig(cfg)
+    if err != nil {
+        return fmt.Errorf("copy config defaults failed, err=%w", err)
     }
 
-    // for dump demo config to file
-    if os.Getenv("XXX_DUMP_DEMO_CFG") != "" || flagResult.DumpConfig() {
-        cl.options.logger.Infow("begin dump demo config")
-        DumpDemoCfg(cfg)
-        os.Exit(0)
+    if cl.options.reloadOnChange {
+        cl.listenProviderChanges()
+    }
+
+    snap, err := cl.loadConfig(cfg)
+    if err != nil {
+        return err
+    }
+    cl.snapshot.Store(snap)
+    cl.metrics.loaded(snap)
+
+    // for dump the effective config
+    if isDump {
+        cl.options.logger.Infow("begin dump effective config")
+        if err := cl.dumpConfig(cfg); err != nil {
+            return err
+        }
+        return ErrConfigDumped
     }
 
     // logging config in toml format
     if cl.options.dumpMarshalledConfig {
-        cl.dumpReMarshalledConfigText(cfg)
+        cl.dumpReMarshalledConfigText(cfg, snap.secrets)
     }
 
     // inspect config
@@ -204,60 +326,391 @@ func (cl *ConfigLoader) Load(cfg interface{}) error {
             return fmt.Errorf("inspect config failed with error: %w", err)
         }
     }
+
+    if cl.options.levelController != nil {
+        if err := cl.options.levelController.Bind(cl); err != nil {
+            return fmt.Errorf("bind log level failed, err=%w", err)
+        }
+    }
+
+    if cl.options.reloadOnSignal {
+        cl.watchReloadSignal()
+    }
+    if cl.options.reloadOnChange {
+        cl.reloadOnProviderChange()
+    }
     return nil
 }
 
-func (cl *ConfigLoader) dumpReMarshalledConfigText(cfg interface{}) {
-    text, err := TomlMarshalIndent(cfg)
+// loadConfig read the providers into cfg, which holds the default values, and run the hook
+func (cl *ConfigLoader) loadConfig(cfg interface{}) (*snapshot, error) {
+    if setter, ok := cfg.(profileSetter); ok {
+        setter.setActiveProfile(cl.profile)
+    }
+
+    tracker := &provenanceTracker{log: cl.options.logger}
+    tracker.record(SourceDefault, cfg)
+
+    layers, err := cl.getConfigViaProviders()
+    if err != nil {
+        return nil, err
+    }
+    content, source, secrets, err := cl.mergeLayers(layers, tracker)
+    if err != nil {
+        return nil, err
+    }
+
+    err = cl.options.unmarshaler(content, cfg)
+    if err != nil {
+        return nil, fmt.Errorf("unmarshal config failed, err=%w", err)
+    }
+    tracker.record("", cfg)
+
+    // the decrypted and resolved values are redacted
+    if len(cl.options.secretResolvers) > 0 {
+        resolved, err := cl.resolveSecrets(cfg)
+        if err != nil {
+            return nil, err
+        }
+        for path := range resolved {
+            secrets[path] = true
+        }
+        tracker.record(SourceSecret, cfg)
+    }
+
+    if baseEmbeded, ok := cfg.(BaseConfigEmbedded); ok {
+        baseEmbeded.InitOtlpGrpcEndpointFromEnv()
+        tracker.record(SourceEnv, cfg)
+    }
+
+    // decrypted and resolved secrets must not be logged
+    redacted, err := redactWith(cfg, secrets)
+    if err != nil {
+        cl.options.logger.Warnw("redact config for logging failed", "err", err)
+    }
+    cl.options.logger.Infow("config loaded successfully", "config", redacted, "provider", source)
+
+    if cl.options.beforeInspectHook != nil {
+        cl.options.beforeInspectHook(cfg)
+        tracker.record(SourceHook, cfg)
+    }
+    hash, err := configHash(cfg)
+    if err != nil {
+        return nil, fmt.Errorf("hash config failed, err=%w", err)
+    }
+    return &snapshot{cfg: cfg, source: source, provenance: tracker.result(), hash: hash, loadedAt: time.Now(), secrets: secrets}, nil
+}
+
+// dumpReMarshalledConfigText print the redacted config to stderr, the values of secrets are never printed
+func (cl *ConfigLoader) dumpReMarshalledConfigText(cfg interface{}, secrets map[string]bool) {
+    redacted, err := redactWith(cfg, secrets)
+    if err != nil {
+        cl.options.logger.Errorw("redact config for dump failed", "format", cl.dumpFormat, "err", err)
+        return
+    }
+    text, err := MarshalIndent(redacted, cl.dumpFormat)
     if err == nil {
-        fmt.Fprintf(os.Stderr, "--------- begin dump toml encoded config --------- :\n%s\n", text)
+        fmt.Fprintf(os.Stderr, "--------- begin dump %s encoded config --------- :\n%s\n", cl.dumpFormat, text)
     } else {
-        cl.options.logger.Errorw("tomlv2.Encode failed", "config", cfg)
+        cl.options.logger.Errorw("marshal config failed", "format", cl.dumpFormat, "err", err)
+    }
+}
+
+// resolveDumpOptions the --dump-* flags take precedence over the With* options
+func (cl *ConfigLoader) resolveDumpOptions(flagResult FlagParseResult) error {
+    format := string(cl.options.dumpFormat)
+    if r, ok := flagResult.(DumpFormatFlagResult); ok && r.DumpFormat() != "" {
+        format = r.DumpFormat()
+    }
+    dumpFormat, err := ParseDumpFormat(format)
+    if err != nil {
+        return err
+    }
+
+    mode := string(cl.options.dumpMode)
+    cl.dumpProvenance = cl.options.dumpProvenance
+    if r, ok := flagResult.(DumpModeFlagResult); ok {
+        if r.DumpMode() != "" {
+            mode = r.DumpMode()
+        }
+        cl.dumpProvenance = cl.dumpProvenance || r.DumpProvenance()
+    }
+    dumpMode, err := ParseDumpMode(mode)
+    if err != nil {
+        return err
+    }
+
+    cl.dumpFormat = dumpFormat
+    cl.dumpMode = dumpMode
+    return nil
+}
+
+// verifySignature verify the content against the trusted keys, no-op if no trusted key configured
+func (cl *ConfigLoader) verifySignature(provider Provider, helper *providerHelper, content []byte) error {
+    if len(cl.trustedKeys) == 0 {
+        return nil
+    }
+    // the content is merged from the files verified one by one
+    if _, ok := provider.(fileVerifier); ok {
+        return nil
+    }
+    signed, ok := provider.(SignedProvider)
+    if !ok {
+        return fmt.Errorf("%w: provider %v does not support signature", ErrSignatureVerification, provider.Name())
+    }
+    sig, err := signed.Signature(helper)
+    if err != nil {
+        return fmt.Errorf("%w: provider=%v err=%v", ErrSignatureVerification, provider.Name(), err)
+    }
+    if err := VerifySignature(content, sig, cl.trustedKeys); err != nil {
+        return fmt.Errorf("provider=%v err=%w", provider.Name(), err)
+    }
+    cl.options.logger.Infow("config signature verified", "provider", provider.Name())
+    return nil
+}
+
+// resolveSecrets resolve the secret references with the registered resolvers, nothing is cached across loads.
+// returns the key paths of the resolved values
+func (cl *ConfigLoader) resolveSecrets(cfg interface{}) (map[string]bool, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), DefaultSecretResolveTimeout)
+    defer cancel()
+    resolution := newSecretResolution(cl.options.secretResolvers)
+    if err := resolution.resolve(ctx, cfg); err != nil {
+        return nil, err
     }
+    return resolution.resolved, nil
 }
 
-func (cl *ConfigLoader) getConfigViaProviders() ([]byte, error) {
+// processContent transform the provider content before unmarshalling,
+// returns the key paths of the decrypted values, which are redacted like secrets
+func (cl *ConfigLoader) processContent(content []byte, interpolated bool) ([]byte, map[string]bool, error) {
+    // the decrypted plaintext is not interpolated
     var err error
-    var content []byte
+    if cl.options.interpolate && !interpolated {
+        content, err = Interpolate(content, cl.options.strictInterpolate)
+        if err != nil {
+            return nil, nil, err
+        }
+    }
+    encrypted, err := encryptedPaths(cl.options.unmarshaler, cl.profile, content)
+    if err != nil {
+        return nil, nil, err
+    }
+    content, err = DecryptValues(content, cl.encryptionKeys)
+    return content, encrypted, err
+}
+
+// configSource is a provider with the name used for logging and provenance
+type configSource struct {
+    name     string
+    provider Provider
+}
+
+// configLayer is the content of a usable provider
+type configLayer struct {
+    source  string
+    content []byte
+    // interpolated by the provider, see documentInterpolator
+    interpolated bool
+}
+
+// resolveConfigSources a single plain --config path is passed to the configured providers as before,
+// otherwise each --config value is created as a provider by its uri scheme and all of them are merged in order
+func (cl *ConfigLoader) resolveConfigSources(flagResult FlagParseResult) error {
+    sources := []string{}
+    if r, ok := flagResult.(ConfigSourcesFlagResult); ok {
+        sources = r.ConfigSources()
+    } else if flagResult.ConfigFile() != "" {
+        sources = append(sources, flagResult.ConfigFile())
+    }
+
+    cl.configFile = ""
+    cl.sources = nil
+    cl.merge = cl.options.mergeProviders
+    if len(sources) == 0 || len(sources) == 1 && !isURISource(sources[0]) {
+        if len(sources) == 1 {
+            cl.configFile = sources[0]
+        }
+        for _, provider := range cl.options.providers {
+            cl.sources = append(cl.sources, configSource{name: provider.Name(), provider: provider})
+        }
+        return nil
+    }
+
+    for _, source := range sources {
+        provider, err := ProviderFromURI(source)
+        if err != nil {
+            return err
+        }
+        cl.sources = append(cl.sources, configSource{name: redactSource(source), provider: provider})
+    }
+    cl.merge = true
+    return nil
+}
 
-    helpr := &providerHelper{
-        configFile: cl.configFile,
-        log:        cl.options.logger,
+// redactSource hide the password of the source uri for logging
+func redactSource(source string) string {
+    if !isURISource(source) || source == SourceStdin {
+        return source
+    }
+    u, err := url.Parse(source)
+    if err != nil {
+        return source
+    }
+    if q := u.Query(); q.Has("token") {
+        q.Set("token", RedactedValue)
+        u.RawQuery = q.Encode()
+    }
+    return u.Redacted()
+}
+
+func (cl *ConfigLoader) providerHelper() *providerHelper {
+    return &providerHelper{
+        configFile:     cl.configFile,
+        log:            cl.options.logger,
+        encryptionKeys: cl.encryptionKeys,
+        unmarshaler:    cl.options.unmarshaler,
+        marshaler:      cl.options.marshaler,
+        profile:        cl.profile,
+        trustedKeys:    cl.trustedKeys,
+        interpolate:    cl.interpolator(),
+    }
+}
+
+// interpolator returns the Interpolate of the providers decoding their documents, nil if interpolation is disabled
+func (cl *ConfigLoader) interpolator() func(content []byte) ([]byte, error) {
+    if !cl.options.interpolate {
+        return nil
+    }
+    return func(content []byte) ([]byte, error) {
+        return Interpolate(content, cl.options.strictInterpolate)
+    }
+}
+
+// mergeLayers process each layer and merge them in order, the later layer wins.
+// the key paths of the decrypted values of all layers are returned
+func (cl *ConfigLoader) mergeLayers(layers []configLayer, tracker *provenanceTracker) ([]byte, string, map[string]bool, error) {
+    names := make([]string, 0, len(layers))
+    docs := make([][]byte, 0, len(layers))
+    decrypted := map[string]bool{}
+    for _, layer := range layers {
+        content, encrypted, err := cl.processContent(layer.content, layer.interpolated)
+        if err != nil {
+            return nil, "", nil, fmt.Errorf("process config from provider %v failed, err=%w", layer.source, err)
+        }
+        for path := range encrypted {
+            decrypted[path] = true
+        }
+        content, err = applyProfileSection(cl.providerHelper(), cl.profile, content)
+        if err != nil {
+            return nil, "", nil, fmt.Errorf("apply profile %v of provider %v failed, err=%w", cl.profile, layer.source, err)
+        }
+        tracker.recordDocument(layer.source, content, cl.options.unmarshaler)
+        names = append(names, layer.source)
+        docs = append(docs, content)
     }
-    for _, provider := range cl.options.providers {
-        content, err = provider.Config(helpr)
+    source := strings.Join(names, ",")
+    if len(docs) == 1 {
+        return docs[0], source, decrypted, nil
+    }
+    content, err := mergeDocuments(cl.providerHelper(), docs)
+    if err != nil {
+        return nil, "", nil, fmt.Errorf("merge config from providers %v failed, err=%w", source, err)
+    }
+    return content, source, decrypted, nil
+}
+
+// getConfigViaProviders returns the content of the first usable provider,
+// or the content of every usable provider in order when merging
+func (cl *ConfigLoader) getConfigViaProviders() ([]configLayer, error) {
+    var err error
+    var layers []configLayer
+
+    helpr := cl.providerHelper()
+    for _, source := range cl.sources {
+        var content []byte
+        start := time.Now()
+        content, err = source.provider.Config(helpr)
         if err == nil {
-            break
+            cl.metrics.observeFetch(source.name, ResultSuccess, start)
+            // a provider serving untrusted content is a hard error, the next provider is not tried
+            if err := cl.verifySignature(source.provider, helpr, content); err != nil {
+                return nil, err
+            }
+            _, interpolated := source.provider.(documentInterpolator)
+            layers = append(layers, configLayer{source: source.name, content: content, interpolated: interpolated})
+            if !cl.merge {
+                break
+            }
+            continue
         }
         if errors.Is(err, ErrSkipProvider) {
-            cl.options.logger.Infow("config provider skipped", "provider", provider.Name(), "reason", err)
-        } else {
-            cl.options.logger.Errorw("try get config via provider failed", "provider", provider.Name(), "err", err)
+            cl.metrics.observeFetch(source.name, ResultSkipped, start)
+            cl.options.logger.Infow("config provider skipped", "provider", source.name, "reason", err)
+            continue
+        }
+        cl.metrics.observeFetch(source.name, ResultError, start)
+        if cl.merge {
+            return nil, fmt.Errorf("get config via provider %v failed, err=%w", source.name, err)
         }
+        cl.options.logger.Errorw("try get config via provider failed", "provider", source.name, "err", err)
     }
 
-    if len(cl.options.providers) == 0 {
-        err = errors.New("error no config provider usable")
+    if len(cl.sources) == 0 {
+        return nil, errors.New("error no config provider usable")
     }
-    return content, err
+    if len(layers) == 0 {
+        if err == nil || errors.Is(err, ErrSkipProvider) {
+            err = errors.New("error no config provider usable, all providers skipped")
+        }
+        return nil, err
+    }
+    return layers, nil
 }
 
 type defaultFlagResult struct {
-    configFile  string
+    configFiles []string
+    profile     string
     dumpConfig  bool
+    dumpFormat  string
+    dumpMode    string
+    provenance  bool
     showHelp    bool
     showVersion bool
     usage       func()
 }
 
 func (f *defaultFlagResult) ConfigFile() string {
-    return f.configFile
+    if len(f.configFiles) == 0 {
+        return ""
+    }
+    return f.configFiles[0]
+}
+
+func (f *defaultFlagResult) ConfigSources() []string {
+    return f.configFiles
+}
+
+func (f *defaultFlagResult) Profile() string {
+    return f.profile
 }
 
 func (f *defaultFlagResult) DumpConfig() bool {
     return f.dumpConfig
 }
 
+func (f *defaultFlagResult) DumpFormat() string {
+    return f.dumpFormat
+}
+
+func (f *defaultFlagResult) DumpMode() string {
+    return f.dumpMode
+}
+
+func (f *defaultFlagResult) DumpProvenance() bool {
+    return f.provenance
+}
+
 func (f *defaultFlagResult) ShowHelp() bool {
     return f.showHelp
 }
@@ -271,8 +724,11 @@ func (f *defaultFlagResult) Usage() func() {
 }
 
 func (cl *ConfigLoader) defaultFlagParser() FlagParseResult {
-    var configFile string
+    var configFiles []string
+    var profile string
     var dumpConfig bool
+    var dumpFormat, dumpMode string
+    var dumpProvenance bool
     var showHelp, showVersion bool
 
     commandLine := pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
@@ -284,8 +740,12 @@ func (cl *ConfigLoader) defaultFlagParser() FlagParseResult {
     }
     commandLine.SortFlags = false
 
-    commandLine.StringVarP(&configFile, FlagConfigFile, "c", "", "config file path")
-    commandLine.BoolVar(&dumpConfig, FlagDumpConfig, false, "dump config to toml")
+    commandLine.StringArrayVarP(&configFiles, FlagConfigFile, "c", nil, "config file path, or repeatable config source uri merged in order: file://, nacos://host:port/namespace/group/dataId, http(s)://, consul://, etcd://, env://PREFIX_, - for stdin")
+    commandLine.StringVar(&profile, FlagProfile, "", "active profile like dev|staging|prod, applies [profiles.<profile>] sections and config.<profile>.toml, see WithProfileEnv")
+    commandLine.BoolVar(&dumpConfig, FlagDumpConfig, false, "dump config, see --dump-format")
+    commandLine.StringVar(&dumpFormat, FlagDumpFormat, "", "dump config format, one of toml|yaml|json|env (default toml)")
+    commandLine.StringVar(&dumpMode, FlagDumpMode, "", "dump demo template with default values or the effective config, one of demo|effective (default demo)")
+    commandLine.BoolVar(&dumpProvenance, FlagDumpProvenance, false, "annotate each key with its source when dumping the effective config")
     commandLine.BoolVarP(&showVersion, "version", "v", false, "display the current version of this CLI")
     commandLine.BoolVarP(&showHelp, "help", "h", false, "show help")
 
@@ -294,5 +754,5 @@ func (cl *ConfigLoader) defaultFlagParser() FlagParseResult {
     }
 
     commandLine.Parse(os.Args[1:])
-    return &defaultFlagResult{configFile, dumpConfig, showHelp, showVersion, commandLine.Usage}
+    return &defaultFlagResult{configFiles, profile, dumpConfig, dumpFormat, dumpMode, dumpProvenance, showHelp, showVersion, commandLine.Usage}
 }
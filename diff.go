@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "changed"
+)
+
+// Change is a changed key path, Old and New are redacted like Redact
+type Change struct {
+	Path string      `json:"path"`
+	Type ChangeType  `json:"type"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Type {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s = %v", c.Path, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s = %v", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %v -> %v", c.Path, c.Old, c.New)
+	}
+}
+
+// Changes are sorted by path
+type Changes []Change
+
+// Under reports whether any key under the prefix changed, e.g. Under("mysql") matches mysql.dsn, empty prefix matches any
+func (cs Changes) Under(prefix string) bool {
+	for _, c := range cs {
+		if hasPathPrefix(c.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Paths returns the changed key paths
+func (cs Changes) Paths() []string {
+	paths := make([]string, len(cs))
+	for i, c := range cs {
+		paths[i] = c.Path
+	}
+	return paths
+}
+
+// Strings returns the changes like "~ log.level: info -> debug" for logging
+func (cs Changes) Strings() []string {
+	lines := make([]string, len(cs))
+	for i, c := range cs {
+		lines[i] = c.String()
+	}
+	return lines
+}
+
+// Diff compare the configs key by key, the configs are structs or pointers to them, or generic maps.
+// the values are compared before redaction, so a changed secret is reported with both values redacted
+func Diff(old, new interface{}) (Changes, error) {
+	return diffWith(old, new, nil)
+}
+
+// diffWith redact the key paths in extra as well, e.g. the values resolved by SecretResolver
+func diffWith(old, new interface{}, extra map[string]bool) (Changes, error) {
+	oldTree, err := toTree(old)
+	if err != nil {
+		return nil, fmt.Errorf("convert old config failed, err=%w", err)
+	}
+	newTree, err := toTree(new)
+	if err != nil {
+		return nil, fmt.Errorf("convert new config failed, err=%w", err)
+	}
+	secrets := secretPaths(old)
+	for path := range secretPaths(new) {
+		secrets[path] = true
+	}
+	for path := range extra {
+		secrets[path] = true
+	}
+	return diffTrees(oldTree, newTree, secrets), nil
+}
+
+// DiffDocuments compare two config documents decoded by the unmarshaler, e.g. TomlUnmarshaler
+func DiffDocuments(old, new []byte, unmarshaler Unmarshaler) (Changes, error) {
+	oldTree, newTree := map[string]interface{}{}, map[string]interface{}{}
+	if err := unmarshaler(old, &oldTree); err != nil {
+		return nil, fmt.Errorf("decode old document failed, err=%w", err)
+	}
+	if err := unmarshaler(new, &newTree); err != nil {
+		return nil, fmt.Errorf("decode new document failed, err=%w", err)
+	}
+	return diffTrees(oldTree, newTree, nil), nil
+}
+
+func diffTrees(oldTree, newTree map[string]interface{}, secrets map[string]bool) Changes {
+	oldKeys, newKeys := map[string]interface{}{}, map[string]interface{}{}
+	flattenTree(oldTree, "", oldKeys)
+	flattenTree(newTree, "", newKeys)
+
+	var changes Changes
+	for path, v := range newKeys {
+		ov, ok := oldKeys[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: path, Type: ChangeAdded, New: redactValue(path, v, secrets)})
+		case !reflect.DeepEqual(ov, v):
+			changes = append(changes, Change{
+				Path: path,
+				Type: ChangeModified,
+				Old:  redactValue(path, ov, secrets),
+				New:  redactValue(path, v, secrets),
+			})
+		}
+	}
+	for path, v := range oldKeys {
+		if _, ok := newKeys[path]; !ok {
+			changes = append(changes, Change{Path: path, Type: ChangeRemoved, Old: redactValue(path, v, secrets)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/kk-kwok/config/version"
+)
+
+// ErrConfigDumped is returned by Load after the config is dumped by --dump, the caller is expected to exit 0, e.g.
+//
+//	if err := loader.Load(cfg); errors.Is(err, config.ErrConfigDumped) {
+//		os.Exit(0)
+//	}
+var ErrConfigDumped = errors.New("config dumped")
+
+// DumpMode select what --dump prints
+type DumpMode string
+
+const (
+	// DumpModeDemo print the config template with the default values only, providers are not consulted
+	DumpModeDemo DumpMode = "demo"
+	// DumpModeEffective print the fully resolved config after defaults, providers, env, flags and hooks, secrets are redacted
+	DumpModeEffective DumpMode = "effective"
+
+	DefaultDumpMode = DumpModeDemo
+)
+
+func ParseDumpMode(s string) (DumpMode, error) {
+	switch m := DumpMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return DefaultDumpMode, nil
+	case DumpModeDemo, DumpModeEffective:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown dump mode %q, must be one of demo|effective", s)
+	}
+}
+
+// provenance sources besides the provider names
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceHook    = "hook"
+)
+
+// Provenance maps the dotted key path to where its value came from,
+// the source is a provider name, "default", "env" or "hook"
+type Provenance map[string]string
+
+// provenanceTracker record the flattened config between the load stages to compute the provenance
+type provenanceTracker struct {
+	log    Logger
+	stages []provenanceStage
+}
+
+type provenanceStage struct {
+	source   string
+	document bool // keys set explicitly by a provider document
+	keys     map[string]interface{}
+}
+
+// record the config struct after a stage, keys changed since the previous recorded struct are attributed to source,
+// empty source only records the baseline
+func (t *provenanceTracker) record(source string, cfg interface{}) {
+	tree, err := toTree(cfg)
+	if err != nil {
+		t.log.Warnw("flatten config for provenance failed", "source", source, "err", err)
+		return
+	}
+	keys := map[string]interface{}{}
+	flattenTree(tree, "", keys)
+	t.stages = append(t.stages, provenanceStage{source: source, keys: keys})
+}
+
+// recordDocument record the keys set by a provider document
+func (t *provenanceTracker) recordDocument(source string, content []byte, unmarshaler Unmarshaler) {
+	tree := map[string]interface{}{}
+	if err := unmarshaler(content, &tree); err != nil {
+		t.log.Warnw("decode config document for provenance failed", "source", source, "err", err)
+		return
+	}
+	keys := map[string]interface{}{}
+	flattenTree(tree, "", keys)
+	t.stages = append(t.stages, provenanceStage{source: source, document: true, keys: keys})
+}
+
+// result attribute every key of the final config to the last stage which set or changed it
+func (t *provenanceTracker) result() Provenance {
+	var final map[string]interface{}
+	for _, stage := range t.stages {
+		if !stage.document {
+			final = stage.keys
+		}
+	}
+	if final == nil {
+		return nil
+	}
+	p := make(Provenance, len(final))
+	for key := range final {
+		p[key] = SourceDefault
+	}
+	var prev map[string]interface{}
+	for _, stage := range t.stages {
+		if stage.document {
+			for key := range stage.keys {
+				if _, ok := p[key]; ok {
+					p[key] = stage.source
+				}
+			}
+			continue
+		}
+		if prev != nil && stage.source != "" {
+			for key, v := range stage.keys {
+				if _, ok := p[key]; ok && !reflect.DeepEqual(prev[key], v) {
+					p[key] = stage.source
+				}
+			}
+		}
+		prev = stage.keys
+	}
+	return p
+}
+
+// Provenance returns where each config key came from in the last Load or Reload
+func (cl *ConfigLoader) Provenance() Provenance {
+	if snap := cl.current(); snap != nil {
+		return snap.provenance
+	}
+	return nil
+}
+
+// dumpConfig dump the config to stdout, the effective mode redacts secrets and optionally appends provenance
+// nolint: forbidigo
+func (cl *ConfigLoader) dumpConfig(cfg interface{}) error {
+	var out interface{} = cfg
+	if cl.dumpMode == DumpModeEffective {
+		redacted, err := redactWith(cfg, cl.current().secrets)
+		if err != nil {
+			return fmt.Errorf("redact config failed, err=%w", err)
+		}
+		out = redacted
+	}
+	text, err := MarshalIndent(out, cl.dumpFormat)
+	if err != nil {
+		return fmt.Errorf("%s marshal failed, err=%w", cl.dumpFormat, err)
+	}
+	if cl.dumpFormat.SupportComments() {
+		fmt.Printf("# %s %s\n# dump mode: %s\n", version.ServiceName, version.Info(), cl.dumpMode)
+	}
+	fmt.Fprintln(os.Stdout, strings.TrimRight(text, "\n"))
+
+	if cl.dumpMode == DumpModeEffective && cl.dumpProvenance {
+		w := os.Stdout
+		if !cl.dumpFormat.SupportComments() {
+			w = os.Stderr
+		}
+		fmt.Fprintln(w, "# provenance (key = source):")
+		provenance := cl.Provenance()
+		for _, key := range sortedKeys(provenance) {
+			fmt.Fprintf(w, "#   %s = %s\n", key, provenance[key])
+		}
+	}
+	fmt.Fprintln(os.Stderr, "config dump success")
+	return nil
+}
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	// EnvEncryptionKey base64 encoded 32 bytes AES-256 key, multiple keys can be separated by comma for rotation
+	EnvEncryptionKey = "CONFIG_ENCRYPTION_KEY"
+	// EnvEncryptionKeyFile path of the key file, one base64 encoded key per line
+	EnvEncryptionKeyFile = "CONFIG_ENCRYPTION_KEY_FILE"
+
+	// EncryptedTypeDocument marks a whole encrypted document
+	EncryptedTypeDocument = "doc"
+	EncryptedTypeString   = "str"
+
+	encryptionKeySize = 32
+	gcmTagSize        = 16
+)
+
+var ErrDecrypt = errors.New("decrypt config failed")
+
+// encryptedValue match the SOPS style encrypted value ENC[AES256_GCM,data:...,iv:...,tag:...,type:str]
+var encryptedValue = regexp.MustCompile(`ENC\[AES256_GCM,data:([A-Za-z0-9+/=]*),iv:([A-Za-z0-9+/=]+),tag:([A-Za-z0-9+/=]+),type:(\w+)\]`)
+
+// GenerateEncryptionKey returns a new base64 encoded AES-256 key
+func GenerateEncryptionKey() (string, error) {
+	key := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseEncryptionKeys parse base64 encoded keys separated by newline or comma, empty lines and # comments are ignored
+func ParseEncryptionKeys(text string) ([][]byte, error) {
+	var keys [][]byte
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, s := range strings.Split(line, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			key, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("decode encryption key failed, err=%w", err)
+			}
+			if len(key) != encryptionKeySize {
+				return nil, fmt.Errorf("invalid encryption key size %d, must be %d bytes", len(key), encryptionKeySize)
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys, scanner.Err()
+}
+
+// LoadEncryptionKeys read the keys from the key file and env CONFIG_ENCRYPTION_KEY_FILE, CONFIG_ENCRYPTION_KEY
+func LoadEncryptionKeys(keyFile string) ([][]byte, error) {
+	var text []string
+	for _, path := range []string{keyFile, os.Getenv(EnvEncryptionKeyFile)} {
+		if path == "" {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read encryption key file failed, err=%w", err)
+		}
+		text = append(text, string(content))
+	}
+	text = append(text, os.Getenv(EnvEncryptionKey))
+	return ParseEncryptionKeys(strings.Join(text, "\n"))
+}
+
+// EncryptValue encrypt the plaintext into ENC[AES256_GCM,...] with the 32 bytes key, typ is str or doc
+func EncryptValue(plaintext []byte, key []byte, typ string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, nil)
+	data, tag := sealed[:len(sealed)-gcmTagSize], sealed[len(sealed)-gcmTagSize:]
+	enc := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]", enc(data), enc(iv), enc(tag), typ), nil
+}
+
+// decryptMatch decrypt the submatches of encryptedValue with the first key that works
+func decryptMatch(m [][]byte, keys [][]byte) ([]byte, error) {
+	var parts [3][]byte
+	for i := range parts {
+		var err error
+		parts[i], err = base64.StdEncoding.DecodeString(string(m[i+1]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid base64, err=%v", ErrDecrypt, err)
+		}
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := gcm.Open(nil, iv, append(append([]byte{}, data...), tag...), nil)
+		if err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no key matched", ErrDecrypt)
+}
+
+// DecryptValues replace the ENC[AES256_GCM,...] values in content with the plaintext.
+// string values are escaped for the toml, yaml or json string they are in like Interpolate,
+// a whole encrypted document of type doc is decrypted as is, the values in # comments are kept encrypted
+func DecryptValues(content []byte, keys [][]byte) ([]byte, error) {
+	matches := encryptedValue.FindAllSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		return content, nil
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(content))
+	scanner := stringScanner{line: 1}
+	pos, written := 0, 0
+	for _, m := range matches {
+		for pos < m[0] {
+			pos = scanner.step(content, pos)
+		}
+		if pos > m[0] || scanner.comment {
+			continue
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("%w: found encrypted value at line %d but no key configured, set %v or %v",
+				ErrDecrypt, scanner.line, EnvEncryptionKey, EnvEncryptionKeyFile)
+		}
+		submatches := make([][]byte, len(m)/2)
+		for i := range submatches {
+			submatches[i] = content[m[2*i]:m[2*i+1]]
+		}
+		plaintext, err := decryptMatch(submatches, keys)
+		if err != nil {
+			return nil, fmt.Errorf("%w at line %d", err, scanner.line)
+		}
+		if string(submatches[4]) == EncryptedTypeString {
+			value, err := scanner.ctx.escape(string(plaintext))
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d, err=%v", ErrDecrypt, scanner.line, err)
+			}
+			plaintext = []byte(value)
+		}
+		buf.Write(content[written:m[0]])
+		buf.Write(plaintext)
+		written, pos = m[1], m[1]
+		scanner.lastToken = ']'
+	}
+	buf.Write(content[written:])
+	return buf.Bytes(), nil
+}
+
+// encryptedPaths returns the key paths of the ENC[AES256_GCM,...] values in the document before decryption,
+// a path in the [profiles.<profile>] section is returned with the path it is applied to as well
+func encryptedPaths(unmarshaler Unmarshaler, profile string, content []byte) (map[string]bool, error) {
+	paths := map[string]bool{}
+	if !encryptedValue.Match(content) {
+		return paths, nil
+	}
+	tree := map[string]interface{}{}
+	if err := unmarshaler(content, &tree); err != nil {
+		return nil, fmt.Errorf("%w: decode config for locating encrypted values failed, err=%v", ErrDecrypt, err)
+	}
+	collectEncrypted(tree, "", paths)
+	section := ProfilesKey + "." + profile + "."
+	for path := range paths {
+		if profile != "" && strings.HasPrefix(path, section) {
+			paths[strings.TrimPrefix(path, section)] = true
+		}
+	}
+	return paths, nil
+}
+
+// collectEncrypted add the path of v if it or any array item in it is an encrypted value
+func collectEncrypted(v interface{}, path string, paths map[string]bool) bool {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, item := range vv {
+			collectEncrypted(item, joinPath(path, k), paths)
+		}
+		return false
+	case []interface{}:
+		found := false
+		for _, item := range vv {
+			// the path of a table in an array is the path of the array
+			if collectEncrypted(item, path, paths) {
+				found = true
+			}
+		}
+		if found {
+			paths[path] = true
+		}
+		return found
+	case string:
+		if encryptedValue.MatchString(vv) {
+			paths[path] = true
+			return true
+		}
+	}
+	return false
+}
+
+// EncryptedProvider wraps a provider whose whole document is encrypted by EncryptValue with type doc,
+// e.g. the output of `configtool encrypt --doc`
+type EncryptedProvider struct {
+	Provider Provider
+}
+
+var _ Provider = &EncryptedProvider{}
+
+func (p *EncryptedProvider) Name() string {
+	return p.Provider.Name()
+}
+
+func (p *EncryptedProvider) Config(helper *providerHelper) ([]byte, error) {
+	content, err := p.Provider.Config(helper)
+	if err != nil {
+		return nil, err
+	}
+	m := encryptedValue.FindSubmatch(bytes.TrimSpace(content))
+	if m == nil || string(m[4]) != EncryptedTypeDocument {
+		return nil, fmt.Errorf("%w: content of provider %v is not an encrypted document", ErrDecrypt, p.Provider.Name())
+	}
+	if len(helper.encryptionKeys) == 0 {
+		return nil, fmt.Errorf("%w: no key configured, set %v or %v", ErrDecrypt, EnvEncryptionKey, EnvEncryptionKeyFile)
+	}
+	return decryptMatch(m, helper.encryptionKeys)
+}
+
+var _ changeWatcher = &EncryptedProvider{}
+
+// addChangeListener the changes of the wrapped provider trigger the reload
+func (p *EncryptedProvider) addChangeListener(listener ChangeListener) {
+	if w, ok := p.Provider.(changeWatcher); ok {
+		w.addChangeListener(listener)
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// walkFields visit the exported fields of the struct v recursively, path is the dotted key path following the toml tags.
+// embedded structs without a toml name are flattened into the parent like go-toml does.
+// fn is called for struct fields as well as leaf fields, return false to skip descending into a struct field
+func walkFields(v reflect.Value, prefix string, fn func(path string, field reflect.StructField, value reflect.Value) bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := tomlFieldName(field)
+		if !ok {
+			continue
+		}
+		value := v.Field(i)
+		if field.Anonymous && name == "" {
+			walkFields(value, prefix, fn)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		path := joinPath(prefix, name)
+		if !fn(path, field, value) {
+			continue
+		}
+		if isStructValue(value) {
+			walkFields(value, path, fn)
+		}
+	}
+}
+
+// tomlFieldName returns the toml key of the field, empty name for untagged embedded struct, ok is false for skipped field
+func tomlFieldName(field reflect.StructField) (name string, ok bool) {
+	if !field.IsExported() && !field.Anonymous {
+		return "", false
+	}
+	tag := field.Tag.Get("toml")
+	if tag == "-" {
+		return "", false
+	}
+	name = strings.Split(tag, ",")[0]
+	if name == "" && !field.Anonymous {
+		name = field.Name
+	}
+	return name, true
+}
+
+func isStructValue(v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.Struct
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// flattenTree flatten the generic config tree into dotted key path -> leaf value
+func flattenTree(tree map[string]interface{}, prefix string, out map[string]interface{}) {
+	for k, v := range tree {
+		path := joinPath(prefix, k)
+		if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+			flattenTree(sub, path, out)
+			continue
+		}
+		out[path] = v
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hasPathPrefix reports whether path equals prefix or is nested under it
+func hasPathPrefix(path, prefix string) bool {
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+".")
+}
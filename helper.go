@@ -11,13 +11,20 @@ import (
 )
 
 // DumpDemoCfg dump the config to stdout and exit the app
-// nolint: forbidigo
 func DumpDemoCfg(cfg interface{}) {
-    // print the version
-    fmt.Printf("# %s %s\n", version.ServiceName, version.Info())
-    text, err := TomlMarshalIndent(cfg)
+    DumpDemoCfgFormat(cfg, DefaultDumpFormat)
+}
+
+// DumpDemoCfgFormat dump the config to stdout in the given format
+// nolint: forbidigo
+func DumpDemoCfgFormat(cfg interface{}, format DumpFormat) {
+    // print the version, json has no comment syntax
+    if format.SupportComments() {
+        fmt.Printf("# %s %s\n", version.ServiceName, version.Info())
+    }
+    text, err := MarshalIndent(cfg, format)
     if err != nil {
-        fmt.Fprintf(os.Stderr, "toml.Marshal failed with error: %v", err)
+        fmt.Fprintf(os.Stderr, "%s marshal failed with error: %v", format, err)
         os.Exit(2)
     }
     fmt.Fprintln(os.Stdout, text)
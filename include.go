@@ -0,0 +1,154 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// IncludeKey is the top-level key listing the files to include, e.g. include = ["common.toml", "secrets/*.toml"]
+	IncludeKey = "include"
+	// MaxIncludeDepth limits the nesting of included files
+	MaxIncludeDepth = 10
+)
+
+var ErrIncludeCycle = errors.New("include cycle detected")
+
+// resolveIncludes expand the include directive of a local config file. the paths are relative to the including file
+// and may contain glob patterns, the included files are merged in order and the including file wins.
+// content is the interpolated content of the file, the included files are interpolated before decoding as well.
+// files returns the config file and all the included files for watching
+func resolveIncludes(helper *providerHelper, path string, content []byte) (resolved []byte, files []string, err error) {
+	files = []string{path}
+	if !bytes.Contains(content, []byte(IncludeKey)) {
+		return content, files, nil
+	}
+	tree := map[string]interface{}{}
+	if err := helper.unmarshaler(content, &tree); err != nil {
+		// the content is interpolated, leave the decode error with position to the loader unmarshaler
+		return content, files, nil
+	}
+	if _, ok := tree[IncludeKey]; !ok {
+		return content, files, nil
+	}
+
+	r := &includeResolver{helper: helper}
+	merged, err := r.resolve(path, tree, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resolved, err = helper.encode(merged)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode included config failed, err=%w", err)
+	}
+	helper.log.Infow("config includes resolved", "config_file", path, "included", r.files)
+	return resolved, append(files, r.files...), nil
+}
+
+type includeResolver struct {
+	helper *providerHelper
+	files  []string
+}
+
+// resolve merge the included files of tree, stack is the chain of including files for cycle detection
+func (r *includeResolver) resolve(path string, tree map[string]interface{}, stack []string) (map[string]interface{}, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range stack {
+		if f == abs {
+			return nil, fmt.Errorf("%w: %v -> %v", ErrIncludeCycle, strings.Join(stack, " -> "), abs)
+		}
+	}
+	stack = append(stack, abs)
+	if len(stack) > MaxIncludeDepth+1 {
+		return nil, fmt.Errorf("include depth exceeds %d: %v", MaxIncludeDepth, strings.Join(stack, " -> "))
+	}
+
+	patterns, err := includePatterns(tree[IncludeKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid include in %v, err=%w", path, err)
+	}
+	delete(tree, IncludeKey)
+
+	merged := map[string]interface{}{}
+	for _, pattern := range patterns {
+		included, err := expandInclude(filepath.Dir(abs), pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include %q in %v failed, err=%w", pattern, path, err)
+		}
+		for _, f := range included {
+			content, err := os.ReadFile(f)
+			if err != nil {
+				return nil, fmt.Errorf("read included file failed, file=%v err=%w", f, err)
+			}
+			if err := verifyFile(r.helper, f, content); err != nil {
+				return nil, err
+			}
+			if content, err = r.helper.interpolateDocument(content); err != nil {
+				return nil, fmt.Errorf("interpolate included file failed, file=%v err=%w", f, err)
+			}
+			sub := map[string]interface{}{}
+			if err := r.helper.unmarshaler(content, &sub); err != nil {
+				return nil, fmt.Errorf("decode included file failed, file=%v err=%w", f, err)
+			}
+			r.files = append(r.files, f)
+			sub, err = r.resolve(f, sub, stack)
+			if err != nil {
+				return nil, err
+			}
+			mergeTree(merged, sub)
+		}
+	}
+	// the including file wins
+	mergeTree(merged, tree)
+	return merged, nil
+}
+
+func includePatterns(v interface{}) ([]string, error) {
+	switch vv := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{vv}, nil
+	case []interface{}:
+		patterns := make([]string, 0, len(vv))
+		for _, item := range vv {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("include item must be string, got %T", item)
+			}
+			patterns = append(patterns, s)
+		}
+		return patterns, nil
+	case []string:
+		return vv, nil
+	default:
+		return nil, fmt.Errorf("include must be a string or an array of strings, got %T", v)
+	}
+}
+
+// expandInclude resolve the pattern relative to dir, a plain path must exist while a glob may match nothing
+func expandInclude(dir, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, err
+		}
+		return []string{pattern}, nil
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,289 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var ErrUndefinedVariable = errors.New("undefined variable")
+
+// interpolateFilePrefix ${file:/run/secrets/db_pass} is replaced by the file content
+const interpolateFilePrefix = "file:"
+
+// Interpolate expand the variables in config content before unmarshalling:
+//
+//	${ENV_VAR}              value of the env var
+//	${ENV_VAR:-default}     default if the env var is unset or empty
+//	${file:/path/to/secret} file content with the trailing newline trimmed
+//	$${ENV_VAR}             escaped, kept literally as ${ENV_VAR}
+//
+// in strict mode an undefined env var without default, an invalid variable name or an unterminated ${ is an error,
+// otherwise the undefined env var is expanded to empty string and the others are kept literally.
+//
+// the variables in # comments are kept as is. the values are escaped for the toml, yaml or json string they are in,
+// a value with quotes or newlines outside of a string is inserted as a double-quoted string,
+// and a value that cannot be represented in a single-quoted string is an error
+func Interpolate(content []byte, strict bool) ([]byte, error) {
+	if !bytes.Contains(content, []byte("${")) {
+		return content, nil
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(content))
+	scanner := stringScanner{line: 1}
+	for i := 0; i < len(content); {
+		if scanner.comment || content[i] != '$' || i+1 >= len(content) {
+			next := scanner.step(content, i)
+			buf.Write(content[i:next])
+			i = next
+			continue
+		}
+		// escaped $${
+		if content[i+1] == '$' && i+2 < len(content) && content[i+2] == '{' {
+			buf.WriteString("${")
+			i += 3
+			scanner.lastToken = '$'
+			continue
+		}
+		if content[i+1] != '{' {
+			next := scanner.step(content, i)
+			buf.Write(content[i:next])
+			i = next
+			continue
+		}
+		end := bytes.IndexByte(content[i+2:], '}')
+		if end < 0 || bytes.IndexByte(content[i+2:i+2+end], '\n') >= 0 {
+			if strict {
+				return nil, fmt.Errorf("unterminated variable at line %d", scanner.line)
+			}
+			next := scanner.step(content, i)
+			buf.Write(content[i:next])
+			i = next
+			continue
+		}
+		expr := string(content[i+2 : i+2+end])
+		value, err := expandVariable(expr, strict)
+		if err == nil {
+			value, err = scanner.ctx.escape(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("interpolate ${%s} at line %d failed, err=%w", expr, scanner.line, err)
+		}
+		buf.WriteString(value)
+		i += 3 + end
+		scanner.lastToken = '$'
+	}
+	return buf.Bytes(), nil
+}
+
+// documentInterpolator is implemented by the providers decoding their documents to resolve includes or merge them,
+// e.g. FileProvider and ConsulProvider. each document is interpolated before decoding,
+// so a bare ${PORT} doesn't break the decoding, and the loader doesn't interpolate the content again
+type documentInterpolator interface {
+	interpolatesDocuments()
+}
+
+// stringScanner track whether the scanned content is in a comment or in a toml, yaml or json string,
+// for replacing values inside the content, e.g. Interpolate and DecryptValues
+type stringScanner struct {
+	ctx       stringContext
+	comment   bool
+	lastToken byte // the last non-space byte of the line outside of strings, 0 at the line start
+	line      int
+}
+
+// step scan the token at content[i] and returns the index after it
+func (s *stringScanner) step(content []byte, i int) int {
+	c := content[i]
+	next := i + 1
+	if c == '\n' {
+		s.line++
+		s.comment = false
+		if s.ctx == stringNone {
+			s.lastToken = 0
+		}
+	}
+	if s.comment {
+		return next
+	}
+	switch s.ctx {
+	case stringNone:
+		switch {
+		case c == '#' && (i == 0 || isSpace(content[i-1])):
+			s.comment = true
+		case (c == '"' || c == '\'') && opensString(s.lastToken):
+			s.ctx = stringBasic
+			if c == '\'' {
+				s.ctx = stringLiteral
+			}
+			if bytes.HasPrefix(content[i:], []byte{c, c, c}) {
+				s.ctx++ // the multi-line variant
+				next = i + 3
+			}
+		case !isSpace(c):
+			s.lastToken = c
+		}
+	case stringBasic, stringMultiBasic:
+		switch {
+		case c == '\\' && next < len(content):
+			if content[next] == '\n' {
+				s.line++
+			}
+			next++
+		case c == '"' && s.ctx == stringBasic:
+			s.ctx, s.lastToken = stringNone, c
+		case c == '"' && bytes.HasPrefix(content[i:], []byte(`"""`)):
+			s.ctx, s.lastToken = stringNone, c
+			next = i + 3
+		}
+	case stringLiteral:
+		if c == '\'' {
+			if next < len(content) && content[next] == '\'' {
+				next++ // '' in yaml single-quoted string
+			} else {
+				s.ctx, s.lastToken = stringNone, c
+			}
+		}
+	case stringMultiLiteral:
+		if bytes.HasPrefix(content[i:], []byte("'''")) {
+			s.ctx, s.lastToken = stringNone, c
+			next = i + 3
+		}
+	}
+	return next
+}
+
+// stringContext is the kind of the toml, yaml or json string at the variable
+type stringContext int
+
+const (
+	stringNone         stringContext = iota
+	stringBasic                      // "..." with backslash escapes
+	stringMultiBasic                 // """..."""
+	stringLiteral                    // '...'
+	stringMultiLiteral               // '''...'''
+)
+
+// escape the value for the string context
+func (ctx stringContext) escape(value string) (string, error) {
+	switch ctx {
+	case stringBasic, stringMultiBasic:
+		return escapeBasic(value), nil
+	case stringLiteral:
+		if strings.ContainsAny(value, "'\r\n") {
+			return "", errors.New("value with quote or newline cannot be in a single-quoted string")
+		}
+	case stringMultiLiteral:
+		if strings.Contains(value, "'''") {
+			return "", errors.New("value with ''' cannot be in a multi-line literal string")
+		}
+	default:
+		if needsQuote(value) {
+			return `"` + escapeBasic(value) + `"`, nil
+		}
+	}
+	return value, nil
+}
+
+// needsQuote reports whether the value outside of a string would be parsed as other than the plain text,
+// e.g. a yaml plain scalar with ": " or " #"
+func needsQuote(value string) bool {
+	if value == "" {
+		return false
+	}
+	if strings.ContainsAny(value, "\"'\\\r\n") || strings.Contains(value, ": ") || strings.Contains(value, " #") || hasControl(value) {
+		return true
+	}
+	for _, prefix := range []string{"- ", "? ", ": "} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return strings.IndexByte("#&*!|>%@` \t", value[0]) >= 0 || strings.HasSuffix(value, " ")
+}
+
+// opensString reports whether a quote after the token starts a string rather than being part of a plain value
+func opensString(lastToken byte) bool {
+	return lastToken == 0 || strings.IndexByte("=:[{,-?", lastToken) >= 0
+}
+
+// escapeBasic escape the value like a json string without the quotes,
+// which is valid in toml basic strings and yaml double-quoted strings
+func escapeBasic(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+func hasControl(s string) bool {
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func expandVariable(expr string, strict bool) (string, error) {
+	if strings.HasPrefix(expr, interpolateFilePrefix) {
+		path := strings.TrimPrefix(expr, interpolateFilePrefix)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	name, def, hasDefault := strings.Cut(expr, ":-")
+	if !isVariableName(name) {
+		if !strict {
+			return "${" + expr + "}", nil
+		}
+		return "", fmt.Errorf("invalid variable name %q", name)
+	}
+	value, ok := os.LookupEnv(name)
+	if hasDefault && value == "" {
+		return def, nil
+	}
+	if !ok && strict {
+		return "", ErrUndefinedVariable
+	}
+	return value, nil
+}
+
+func isVariableName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
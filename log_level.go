@@ -0,0 +1,238 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultLevelOverrideTTL is how long a level set via LevelController.ServeHTTP lasts without ttl
+const DefaultLevelOverrideTTL = 10 * time.Minute
+
+// NewZapLogger build the zap logger from the log config, the returned AtomicLevel changes the level at runtime,
+// e.g. with LevelController
+func NewZapLogger(cfg LoggerConfig) (*zap.Logger, zap.AtomicLevel, error) {
+	level, err := parseLevel(cfg.GetLevel())
+	if err != nil {
+		return nil, zap.AtomicLevel{}, err
+	}
+	atomicLevel := zap.NewAtomicLevelAt(level)
+
+	zapCfg := zap.NewProductionConfig()
+	zapCfg.Level = atomicLevel
+	zapCfg.Encoding = cfg.GetEncoding()
+	if zapCfg.Encoding == "" {
+		zapCfg.Encoding = string(DefaultLogEncoding)
+	}
+	if zapCfg.Encoding == string(LogEncodingConsole) {
+		zapCfg.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	}
+	output := cfg.GetOutput()
+	if output == "" {
+		output = DefaultLogOutput
+	}
+	zapCfg.OutputPaths = []string{output}
+	zapCfg.DisableStacktrace = cfg.GetDisableStacktrace()
+	zapCfg.InitialFields = cfg.GetInitialFields()
+
+	logger, err := zapCfg.Build()
+	if err != nil {
+		return nil, zap.AtomicLevel{}, fmt.Errorf("build zap logger failed, err=%w", err)
+	}
+	return logger, atomicLevel, nil
+}
+
+func parseLevel(s string) (zapcore.Level, error) {
+	if s == "" {
+		s = DefaultLogLevel
+	}
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return level, fmt.Errorf("invalid log level %q, err=%w", s, err)
+	}
+	return level, nil
+}
+
+// LevelController drive the AtomicLevel by the log.level config on reload, and by temporary overrides via ServeHTTP
+// which revert to the config level after the ttl
+type LevelController struct {
+	level zap.AtomicLevel
+
+	mu        sync.Mutex
+	base      zapcore.Level // the level from config
+	expiresAt time.Time     // zero if not overridden
+	timer     *time.Timer
+	// generation is increased by each Override and revert, a fired timer of an older override is ignored
+	generation uint64
+	bound      map[*ConfigLoader]bool
+}
+
+func NewLevelController(level zap.AtomicLevel) *LevelController {
+	return &LevelController{level: level, base: level.Level()}
+}
+
+// Level returns the current level
+func (c *LevelController) Level() zapcore.Level {
+	return c.level.Level()
+}
+
+// SetConfigLevel set the level from config, empty is info. it is applied when no override is active
+func (c *LevelController) SetConfigLevel(s string) error {
+	level, err := parseLevel(s)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.base = level
+	if c.expiresAt.IsZero() {
+		c.level.SetLevel(level)
+	}
+	return nil
+}
+
+// Override set the level for ttl then revert to the config level, ttl <= 0 uses DefaultLevelOverrideTTL
+func (c *LevelController) Override(level zapcore.Level, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		ttl = DefaultLevelOverrideTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.generation++
+	generation := c.generation
+	c.level.SetLevel(level)
+	c.expiresAt = time.Now().Add(ttl)
+	// the timer may have fired and wait for the lock when a new override stops it, the generation tells
+	c.timer = time.AfterFunc(ttl, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.generation == generation {
+			c.revertLocked()
+		}
+	})
+	return c.expiresAt
+}
+
+// revert to the config level
+func (c *LevelController) revert() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.revertLocked()
+}
+
+func (c *LevelController) revertLocked() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.generation++
+	c.expiresAt = time.Time{}
+	c.level.SetLevel(c.base)
+}
+
+// Bind apply the log.level of the loaded config and follow it on reload, binding the same loader again
+// only applies the level
+func (c *LevelController) Bind(cl *ConfigLoader) error {
+	if cfg, ok := cl.Current().(interface{ LogConfig() *LogConfig }); ok {
+		if err := c.SetConfigLevel(cfg.LogConfig().Level); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	bound := c.bound[cl]
+	if !bound {
+		if c.bound == nil {
+			c.bound = map[*ConfigLoader]bool{}
+		}
+		c.bound[cl] = true
+	}
+	c.mu.Unlock()
+	if bound {
+		return nil
+	}
+	return Subscribe(cl, "log.level", func(old, new string) {
+		if err := c.SetConfigLevel(new); err != nil {
+			cl.options.logger.Errorw("apply reloaded log level failed", "level", new, "err", err)
+			return
+		}
+		cl.options.logger.Infow("log level changed by config", "old", old, "new", new)
+	})
+}
+
+type levelPayload struct {
+	Level     *zapcore.Level `json:"level,omitempty"`
+	TTL       string         `json:"ttl,omitempty"`
+	ExpiresAt *time.Time     `json:"expires_at,omitempty"`
+}
+
+type levelError struct {
+	Error string `json:"error"`
+}
+
+// ServeHTTP is compatible with zap.AtomicLevel.ServeHTTP, GET returns {"level":"info"},
+// PUT {"level":"debug","ttl":"5m"} or level=debug&ttl=5m overrides the level until the ttl, default 10m.
+// DELETE reverts to the config level
+func (c *LevelController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		level, ttl, err := decodeLevelRequest(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, levelError{Error: err.Error()})
+			return
+		}
+		c.Override(level, ttl)
+	case http.MethodDelete:
+		c.revert()
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, levelError{Error: "Only GET, PUT and DELETE are supported."})
+		return
+	}
+
+	level := c.Level()
+	resp := levelPayload{Level: &level}
+	c.mu.Lock()
+	if !c.expiresAt.IsZero() {
+		expiresAt := c.expiresAt
+		resp.ExpiresAt = &expiresAt
+	}
+	c.mu.Unlock()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func decodeLevelRequest(r *http.Request) (zapcore.Level, time.Duration, error) {
+	var req levelPayload
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		level, err := parseLevel(r.FormValue("level"))
+		if err != nil || r.FormValue("level") == "" {
+			return level, 0, errors.New("must specify a valid logging level")
+		}
+		req.Level = &level
+		req.TTL = r.FormValue("ttl")
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return 0, 0, fmt.Errorf("request body must be well-formed JSON: %v", err)
+	}
+	if req.Level == nil {
+		return 0, 0, errors.New("must specify a logging level")
+	}
+	if req.TTL == "" {
+		req.TTL = r.URL.Query().Get("ttl")
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil {
+			return 0, 0, fmt.Errorf("invalid ttl %q, err=%v", req.TTL, err)
+		}
+	}
+	return *req.Level, ttl, nil
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tomlv2 "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// DumpFormat is the output format used when dumping config
+type DumpFormat string
+
+const (
+	DumpFormatTOML DumpFormat = "toml"
+	DumpFormatYAML DumpFormat = "yaml"
+	DumpFormatJSON DumpFormat = "json"
+	// DumpFormatEnv renders the config as KEY=value lines, nested keys are joined with EnvKeySeparator like EnvProvider reads them
+	DumpFormatEnv DumpFormat = "env"
+
+	DefaultDumpFormat = DumpFormatTOML
+)
+
+// ParseDumpFormat parse the format name, empty string means DefaultDumpFormat
+func ParseDumpFormat(s string) (DumpFormat, error) {
+	switch f := DumpFormat(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return DefaultDumpFormat, nil
+	case DumpFormatTOML, DumpFormatYAML, DumpFormatJSON, DumpFormatEnv:
+		return f, nil
+	case "yml":
+		return DumpFormatYAML, nil
+	case "dotenv":
+		return DumpFormatEnv, nil
+	default:
+		return "", fmt.Errorf("unknown dump format %q, must be one of toml|yaml|json|env", s)
+	}
+}
+
+// SupportComments report whether the format allows "#" comment lines
+func (f DumpFormat) SupportComments() bool {
+	return f != DumpFormatJSON
+}
+
+// MarshalIndent encode the config in the given format
+func MarshalIndent(cfg interface{}, format DumpFormat) (string, error) {
+	switch format {
+	case DumpFormatTOML, "":
+		return TomlMarshalIndent(cfg)
+	case DumpFormatYAML:
+		return YamlMarshalIndent(cfg)
+	case DumpFormatJSON:
+		return JSONMarshalIndent(cfg)
+	case DumpFormatEnv:
+		return EnvMarshal(cfg)
+	default:
+		return "", fmt.Errorf("unknown dump format %q", format)
+	}
+}
+
+// YamlMarshalIndent encode the config to yaml, the config is converted into a map following the toml tags first
+// since yaml.v3 requires `yaml:",inline"` on the embedded Base struct
+func YamlMarshalIndent(cfg interface{}) (string, error) {
+	tree, err := toTree(cfg)
+	if err != nil {
+		return "", err
+	}
+	buf := bytes.Buffer{}
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(tree); err != nil {
+		return "", err
+	}
+	err = enc.Close()
+	return buf.String(), err
+}
+
+// JSONMarshalIndent encode the config to json, the key names follow the toml tags like the other formats
+func JSONMarshalIndent(cfg interface{}) (string, error) {
+	tree, err := toTree(cfg)
+	if err != nil {
+		return "", err
+	}
+	text, err := json.MarshalIndent(tree, "", "  ")
+	return string(text), err
+}
+
+// EnvMarshal render the config as env file lines like LOG__LEVEL=info, the key names follow the toml tags.
+// arrays and inline tables are rendered as JSON. with a prefix added, the lines can be read back by EnvProvider
+func EnvMarshal(cfg interface{}) (string, error) {
+	tree, err := toTree(cfg)
+	if err != nil {
+		return "", err
+	}
+	lines := []string{}
+	flattenEnv(tree, "", &lines)
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// toTree convert config struct into generic map via toml re-marshalling, so key names follow the toml tags
+func toTree(cfg interface{}) (map[string]interface{}, error) {
+	if tree, ok := cfg.(map[string]interface{}); ok {
+		return tree, nil
+	}
+	text, err := tomlv2.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tree := map[string]interface{}{}
+	if err := tomlv2.Unmarshal(text, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func flattenEnv(tree map[string]interface{}, prefix string, lines *[]string) {
+	for k, v := range tree {
+		key := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(k))
+		if prefix != "" {
+			key = prefix + EnvKeySeparator + key
+		}
+		if sub, ok := v.(map[string]interface{}); ok {
+			flattenEnv(sub, key, lines)
+			continue
+		}
+		*lines = append(*lines, key+"="+envValue(v))
+	}
+}
+
+func envValue(v interface{}) string {
+	var s string
+	switch vv := v.(type) {
+	case string:
+		s = vv
+	case []interface{}, map[string]interface{}:
+		text, err := json.Marshal(vv)
+		if err != nil {
+			return strconv.Quote(fmt.Sprint(vv))
+		}
+		s = string(text)
+	default:
+		s = fmt.Sprint(vv)
+	}
+	if strings.ContainsAny(s, " \t\r\n\"'#$\\`") {
+		return strconv.Quote(s)
+	}
+	return s
+}
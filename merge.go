@@ -0,0 +1,37 @@
+package config
+
+import "fmt"
+
+// mergeDocuments decode the documents with the loader unmarshaler and deep merge them in order, the later wins.
+// the result is encoded with the loader marshaler, a single document is returned as is
+func mergeDocuments(helper *providerHelper, docs [][]byte) ([]byte, error) {
+	if len(docs) == 1 {
+		return docs[0], nil
+	}
+	merged := map[string]interface{}{}
+	for i, doc := range docs {
+		tree := map[string]interface{}{}
+		if err := helper.unmarshaler(doc, &tree); err != nil {
+			return nil, fmt.Errorf("decode document %d for merging failed, err=%w", i, err)
+		}
+		mergeTree(merged, tree)
+	}
+	return helper.encode(merged)
+}
+
+// mergeTree deep merge src into dst, tables are merged recursively, other values are replaced
+func mergeTree(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, ok := v.(map[string]interface{})
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		dstMap, ok := dst[k].(map[string]interface{})
+		if !ok {
+			dstMap = map[string]interface{}{}
+			dst[k] = dstMap
+		}
+		mergeTree(dstMap, srcMap)
+	}
+}
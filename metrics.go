@@ -0,0 +1,136 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// the results of the config_provider_fetch_duration_seconds and config_reloads_total metrics
+const (
+	ResultSuccess  = "success"
+	ResultSkipped  = "skipped"
+	ResultError    = "error"
+	ResultFailure  = "failure"
+	ResultRejected = "rejected"
+	// ResultRolledBack the reloaded config is swapped in then rolled back by the failed health checks
+	ResultRolledBack = "rolled_back"
+)
+
+// loaderMetrics the methods are no-op on nil, which is the loader without WithMetricsRegisterer
+type loaderMetrics struct {
+	fetchDuration *prometheus.HistogramVec
+	reloads       *prometheus.CounterVec
+	lastSuccess   prometheus.Gauge
+	info          *prometheus.GaugeVec
+	restart       prometheus.Gauge
+}
+
+func newLoaderMetrics(registerer prometheus.Registerer) (*loaderMetrics, error) {
+	m := &loaderMetrics{
+		fetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "config_provider_fetch_duration_seconds",
+			Help:    "Duration of fetching the config from the provider, labeled by provider and result of success, skipped or error.",
+			Buckets: []float64{.005, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"provider", "result"}),
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "config_reloads_total",
+			Help: "Total number of config reloads, labeled by result of success, failure, rejected or rolled_back.",
+		}, []string{"result"}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "config_last_success_timestamp_seconds",
+			Help: "Timestamp of the last successful config load or reload.",
+		}),
+		info: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "config_info",
+			Help: "A metric with a constant '1' value labeled by the sha256 of the effective config and the provider which served it.",
+		}, []string{"sha256", "provider"}),
+		restart: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "config_restart_required",
+			Help: "1 if a reload changed fields tagged with reload:\"restart\" and the process runs with the old values.",
+		}),
+	}
+	var err error
+	if m.fetchDuration, err = registerCollector(registerer, m.fetchDuration); err != nil {
+		return nil, err
+	}
+	if m.reloads, err = registerCollector(registerer, m.reloads); err != nil {
+		return nil, err
+	}
+	if m.lastSuccess, err = registerCollector(registerer, m.lastSuccess); err != nil {
+		return nil, err
+	}
+	if m.info, err = registerCollector(registerer, m.info); err != nil {
+		return nil, err
+	}
+	if m.restart, err = registerCollector(registerer, m.restart); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// registerCollector returns the registered one if the loader is created again, e.g. in tests
+func registerCollector[C prometheus.Collector](registerer prometheus.Registerer, c C) (C, error) {
+	err := registerer.Register(c)
+	if err == nil {
+		return c, nil
+	}
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		if existing, ok := already.ExistingCollector.(C); ok {
+			return existing, nil
+		}
+	}
+	return c, fmt.Errorf("register config loader metrics failed, err=%w", err)
+}
+
+func (m *loaderMetrics) observeFetch(provider, result string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.fetchDuration.WithLabelValues(provider, result).Observe(time.Since(start).Seconds())
+}
+
+func (m *loaderMetrics) reloaded(result string) {
+	if m == nil {
+		return
+	}
+	m.reloads.WithLabelValues(result).Inc()
+}
+
+func (m *loaderMetrics) restartRequired() {
+	if m == nil {
+		return
+	}
+	m.restart.Set(1)
+}
+
+// loaded record the config served by the last successful load or reload
+func (m *loaderMetrics) loaded(snap *snapshot) {
+	if m == nil {
+		return
+	}
+	m.lastSuccess.Set(float64(snap.loadedAt.Unix()))
+	m.info.Reset()
+	m.info.WithLabelValues(snap.hash, snap.source).Set(1)
+}
+
+// configHash returns the sha256 of the effective config, the keys are sorted so
+// the hash only changes with the values, not with the provider format or field order
+func configHash(cfg interface{}) (string, error) {
+	tree, err := toTree(cfg)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -2,7 +2,10 @@ package config
 
 import (
 	"errors"
+	"os"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/pflag"
 )
 
@@ -15,6 +18,7 @@ type (
 	InspectConfig     func(config interface{}) error
 	BeforeInspectHook func(config interface{})
 	Unmarshaler       func(p []byte, v interface{}) error
+	Marshaler         func(v interface{}) ([]byte, error)
 	FlagParser        func() FlagParseResult
 )
 
@@ -40,13 +44,44 @@ type options struct {
 	serviceVersion string
 
 	dumpMarshalledConfig bool
+	dumpFormat           DumpFormat
+	dumpMode             DumpMode
+	dumpProvenance       bool
 
 	registerFlags     RegisterFlags
 	inspectConfig     InspectConfig
 	beforeInspectHook BeforeInspectHook
 
+	interpolate       bool
+	strictInterpolate bool
+
+	secretResolvers   []SecretResolver
+	encryptionKeyFile string
+	trustedKeys       []string
+
 	unmarshaler Unmarshaler
+	marshaler   Marshaler
 	providers   []Provider // file, nacos, text
+
+	mergeProviders bool
+	profile        string
+	profileEnv     string
+
+	metricsRegisterer prometheus.Registerer
+
+	reloadOnSignal bool
+	reloadSignals  []os.Signal
+	reloadOnChange bool
+
+	restartRequiredHandler    RestartRequiredHandler
+	shutdownOnRestartRequired bool
+
+	levelController *LevelController
+
+	reloadHealthCheckTimeout time.Duration
+	snapshotHistory          int
+
+	adminToken string
 }
 
 type Option interface {
@@ -104,6 +139,147 @@ func WithCustomUnmarshaler(opt Unmarshaler) Option {
 	})
 }
 
+// WithInterpolation expand ${ENV_VAR}, ${ENV_VAR:-default} and ${file:/path} in the provider content before unmarshalling
+func WithInterpolation(opt bool) Option {
+	return optionFunc(func(o *options) {
+		o.interpolate = opt
+	})
+}
+
+// WithStrictInterpolation enable interpolation and fail the loading on undefined variables
+func WithStrictInterpolation(opt bool) Option {
+	return optionFunc(func(o *options) {
+		o.interpolate = o.interpolate || opt
+		o.strictInterpolate = opt
+	})
+}
+
+// WithEncryptionKeyFile set the key file for decrypting ENC[AES256_GCM,...] values, one base64 encoded key per line.
+// keys from env CONFIG_ENCRYPTION_KEY_FILE and CONFIG_ENCRYPTION_KEY are always loaded
+func WithEncryptionKeyFile(opt string) Option {
+	return optionFunc(func(o *options) {
+		o.encryptionKeyFile = opt
+	})
+}
+
+// WithTrustedKeys require the provider content carry a detached signature by one of the ed25519 public keys,
+// minisign public keys and raw base64 keys are accepted. keys from env CONFIG_TRUSTED_KEYS are appended
+func WithTrustedKeys(opt ...string) Option {
+	return optionFunc(func(o *options) {
+		o.trustedKeys = append(o.trustedKeys, opt...)
+	})
+}
+
+// WithSecretResolvers resolve secret references like vault://kv/data/mysql#password in string fields after unmarshalling
+func WithSecretResolvers(opt ...SecretResolver) Option {
+	return optionFunc(func(o *options) {
+		o.secretResolvers = append(o.secretResolvers, opt...)
+	})
+}
+
+// WithCustomMarshaler set the marshaler paired with the custom unmarshaler, used when merging documents
+func WithCustomMarshaler(opt Marshaler) Option {
+	return optionFunc(func(o *options) {
+		o.marshaler = opt
+	})
+}
+
+// WithMergeProviders merge the content of every usable provider in order instead of using the first one,
+// the later provider wins. skipped providers are ignored and other errors fail the loading
+func WithMergeProviders(opt bool) Option {
+	return optionFunc(func(o *options) {
+		o.mergeProviders = opt
+	})
+}
+
+// WithProfile set the active profile like dev, staging or prod, the --profile flag takes precedence.
+// the [profiles.<profile>] section of each document and the sibling file like config.prod.toml
+// of the local config file are applied on top of the base
+func WithProfile(opt string) Option {
+	return optionFunc(func(o *options) {
+		o.profile = opt
+	})
+}
+
+// WithProfileEnv read the active profile from the env var like EnvAppEnv if neither --profile nor WithProfile is set,
+// the env var is ignored by default
+func WithProfileEnv(opt string) Option {
+	return optionFunc(func(o *options) {
+		o.profileEnv = opt
+	})
+}
+
+// WithReloadOnSignal reload the config from all providers on the signals after Load, default is SIGHUP.
+// the reloaded config is validated by WithInspectConfig and published to the OnReload listeners, stop it with Close
+func WithReloadOnSignal(signals ...os.Signal) Option {
+	return optionFunc(func(o *options) {
+		o.reloadOnSignal = true
+		o.reloadSignals = signals
+	})
+}
+
+// WithReloadOnChange reload the config from all providers when a provider reports a change after Load,
+// i.e. the file, dir, nacos, consul, etcd and http (with PollInterval) providers. the ChangeListener set on
+// the provider is still called before the reload, the changes reported during a reload are coalesced. stop it with Close
+func WithReloadOnChange(opt bool) Option {
+	return optionFunc(func(o *options) {
+		o.reloadOnChange = opt
+	})
+}
+
+// WithRestartRequired set the handler called when a reload changes the fields tagged with `reload:"restart"`
+func WithRestartRequired(opt RestartRequiredHandler) Option {
+	return optionFunc(func(o *options) {
+		o.restartRequiredHandler = opt
+	})
+}
+
+// WithShutdownOnRestartRequired send SIGTERM to the process for a graceful shutdown when a reload
+// changes the fields tagged with `reload:"restart"`, the orchestrator is expected to restart it
+func WithShutdownOnRestartRequired(opt bool) Option {
+	return optionFunc(func(o *options) {
+		o.shutdownOnRestartRequired = opt
+	})
+}
+
+// WithLevelController apply log.level to the controller after Load and on reload,
+// and serve it on /log/level of the AdminHandler
+func WithLevelController(opt *LevelController) Option {
+	return optionFunc(func(o *options) {
+		o.levelController = opt
+	})
+}
+
+// WithReloadHealthCheckTimeout set the deadline of the health checks added by AddReloadHealthCheck, default is 10s
+func WithReloadHealthCheckTimeout(opt time.Duration) Option {
+	return optionFunc(func(o *options) {
+		o.reloadHealthCheckTimeout = opt
+	})
+}
+
+// WithSnapshotHistory set the number of the previous configs retained for History, default is 5, negative disables it
+func WithSnapshotHistory(opt int) Option {
+	return optionFunc(func(o *options) {
+		o.snapshotHistory = opt
+	})
+}
+
+// WithMetricsRegisterer register the loader metrics like config_reloads_total and config_info,
+// e.g. WithMetricsRegisterer(prometheus.DefaultRegisterer)
+func WithMetricsRegisterer(opt prometheus.Registerer) Option {
+	return optionFunc(func(o *options) {
+		o.metricsRegisterer = opt
+	})
+}
+
+// WithAdminToken require the requests to the AdminHandler carry the header "Authorization: Bearer <token>",
+// the token defaults to env CONFIG_ADMIN_TOKEN
+func WithAdminToken(opt string) Option {
+	return optionFunc(func(o *options) {
+		o.adminToken = opt
+	})
+}
+
 func WithProviders(opt ...Provider) Option {
 	return optionFunc(func(o *options) {
 		o.providers = opt
@@ -116,6 +292,28 @@ func WithDumpMarshalledConfig(opt bool) Option {
 	})
 }
 
+// WithDumpFormat set the format used by --dump and WithDumpMarshalledConfig, default is toml.
+// the --dump-format flag takes precedence
+func WithDumpFormat(opt DumpFormat) Option {
+	return optionFunc(func(o *options) {
+		o.dumpFormat = opt
+	})
+}
+
+// WithDumpMode set what --dump prints, the demo template or the effective config. the --dump-mode flag takes precedence
+func WithDumpMode(opt DumpMode) Option {
+	return optionFunc(func(o *options) {
+		o.dumpMode = opt
+	})
+}
+
+// WithDumpProvenance annotate each key with its source when dumping the effective config
+func WithDumpProvenance(opt bool) Option {
+	return optionFunc(func(o *options) {
+		o.dumpProvenance = opt
+	})
+}
+
 func WithServiceName(opt string) Option {
 	return optionFunc(func(o *options) {
 		o.serviceName = opt
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ProfilesKey is the top-level table of the profile override sections, e.g. [profiles.prod.log]
+	ProfilesKey = "profiles"
+
+	FlagProfile = "profile"
+
+	// EnvAppEnv is the conventional env var of the active profile, see WithProfileEnv
+	EnvAppEnv = "APP_ENV"
+)
+
+// ProfileFlagResult is an optional interface of FlagParseResult, for custom flag parser to select the profile
+type ProfileFlagResult interface {
+	Profile() string
+}
+
+// resolveProfile the --profile flag takes precedence over WithProfile and the env var of WithProfileEnv
+func (cl *ConfigLoader) resolveProfile(flagResult FlagParseResult) {
+	cl.profile = cl.options.profile
+	if r, ok := flagResult.(ProfileFlagResult); ok && r.Profile() != "" {
+		cl.profile = r.Profile()
+	}
+	if cl.profile == "" && cl.options.profileEnv != "" {
+		cl.profile = os.Getenv(cl.options.profileEnv)
+	}
+}
+
+// Profile returns the active profile of the last Load, empty if no profile is active
+func (cl *ConfigLoader) Profile() string {
+	return cl.profile
+}
+
+// applyProfileSection merge the [profiles.<profile>] section of the document on top of it, it is applied
+// to each provider layer by the loader only. the profiles table is always removed so it never reaches the config struct
+func applyProfileSection(helper *providerHelper, profile string, content []byte) ([]byte, error) {
+	if !bytes.Contains(content, []byte(ProfilesKey)) {
+		return content, nil
+	}
+	tree := map[string]interface{}{}
+	if err := helper.unmarshaler(content, &tree); err != nil {
+		// leave the decode error with position to the loader unmarshaler
+		return content, nil
+	}
+	profiles, ok := tree[ProfilesKey]
+	if !ok {
+		return content, nil
+	}
+	sections, ok := profiles.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%v must be a table, got %T", ProfilesKey, profiles)
+	}
+	delete(tree, ProfilesKey)
+	if section, ok := sections[profile]; ok && profile != "" {
+		overrides, ok := section.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v.%v must be a table, got %T", ProfilesKey, profile, section)
+		}
+		mergeTree(tree, overrides)
+		// a profiles table inside the section, e.g. of the profile overlay file, is not applied
+		delete(tree, ProfilesKey)
+		helper.log.Infow("config profile section applied", "profile", profile)
+	}
+	return helper.encode(tree)
+}
+
+// profileFile returns the sibling overlay of the config file, e.g. config.prod.toml of config.toml
+func profileFile(configFile, profile string) string {
+	if profile == "" {
+		return ""
+	}
+	ext := filepath.Ext(configFile)
+	return strings.TrimSuffix(configFile, ext) + "." + profile + ext
+}
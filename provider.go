@@ -1,11 +1,35 @@
 package config
 
+import "errors"
+
 type Provider interface {
 	Name() string
 	Config(*providerHelper) ([]byte, error)
 }
 
 type providerHelper struct {
-	configFile string
-	log        Logger
+	configFile     string
+	log            Logger
+	encryptionKeys [][]byte
+	unmarshaler    Unmarshaler
+	marshaler      Marshaler
+	profile        string // the active profile, see WithProfile
+	trustedKeys    []*PublicKey
+	interpolate    func(content []byte) ([]byte, error) // nil if interpolation is disabled
+}
+
+// interpolateDocument expand the variables of a document before the provider decodes it, no-op if interpolation is disabled
+func (h *providerHelper) interpolateDocument(content []byte) ([]byte, error) {
+	if h.interpolate == nil {
+		return content, nil
+	}
+	return h.interpolate(content)
+}
+
+// encode the merged document with the marshaler paired with the loader unmarshaler
+func (h *providerHelper) encode(v interface{}) ([]byte, error) {
+	if h.marshaler == nil {
+		return nil, errors.New("merging documents requires WithCustomMarshaler when using a custom unmarshaler")
+	}
+	return h.marshaler(v)
 }
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EnvConsulHTTPAddr  = "CONSUL_HTTP_ADDR"
+	EnvConsulHTTPToken = "CONSUL_HTTP_TOKEN"
+
+	DefaultConsulAddr     = "http://127.0.0.1:8500"
+	DefaultConsulWaitTime = 5 * time.Minute
+	consulRetryInterval   = 5 * time.Second
+	// consulQueryInterval is the minimum interval of the blocking queries returned without the index moved forward
+	consulQueryInterval = time.Second
+)
+
+// ConsulProvider read the config from consul kv via the http api. with Prefix set, all keys under Key are merged in lexical order.
+// with ChangeListener set, blocking queries on X-Consul-Index watch the key and ChangeListener(Datacenter, "", Key, content) is called on change
+type ConsulProvider struct {
+	Addr       string // default from env CONSUL_HTTP_ADDR or http://127.0.0.1:8500
+	Token      string // acl token, default from env CONSUL_HTTP_TOKEN
+	Datacenter string
+	Key        string
+	Prefix     bool
+
+	TLSConfig  *tls.Config
+	HTTPClient *http.Client
+
+	WaitTime       time.Duration // blocking query wait time, default 5m
+	ChangeListener ChangeListener
+
+	mu        sync.Mutex
+	client    *http.Client
+	index     uint64
+	content   []byte
+	watchOnce sync.Once
+	stop      chan struct{}
+}
+
+var (
+	_ Provider             = &ConsulProvider{}
+	_ documentInterpolator = &ConsulProvider{}
+)
+
+// interpolatesDocuments each key under the prefix is interpolated before merging
+func (p *ConsulProvider) interpolatesDocuments() {}
+
+type consulKVPair struct {
+	Key         string
+	Value       []byte // base64 in json
+	ModifyIndex uint64
+}
+
+func (p *ConsulProvider) Name() string {
+	return "consul"
+}
+
+func (p *ConsulProvider) Config(helper *providerHelper) ([]byte, error) {
+	if p.Key == "" {
+		return nil, fmt.Errorf("%w: empty consul key", ErrSkipProvider)
+	}
+	content, index, err := p.read(context.Background(), helper, 0)
+	if err != nil {
+		return nil, fmt.Errorf("read config from consul failed, key=%v err=%w", p.Key, err)
+	}
+	if index < 1 {
+		index = 1
+	}
+	p.mu.Lock()
+	p.index, p.content = index, content
+	p.mu.Unlock()
+	helper.log.Infow("read config from consul success", "key", p.Key, "datacenter", p.Datacenter, "index", index)
+
+	if p.ChangeListener != nil {
+		p.watchOnce.Do(func() {
+			stop := make(chan struct{})
+			p.mu.Lock()
+			p.stop = stop
+			p.mu.Unlock()
+			go p.watch(helper, stop)
+		})
+	}
+	return content, nil
+}
+
+// Close stop the watching
+func (p *ConsulProvider) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
+
+func (p *ConsulProvider) watch(helper *providerHelper, stop chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	for ctx.Err() == nil {
+		p.mu.Lock()
+		index := p.index
+		p.mu.Unlock()
+
+		start := time.Now()
+		content, newIndex, err := p.read(ctx, helper, index)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			helper.log.Warnw("watch consul config failed", "key", p.Key, "err", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(consulRetryInterval):
+			}
+			continue
+		}
+		// the index may go backwards after a snapshot restore, reset it as the consul docs recommend.
+		// it is kept at least 1 since index 0 makes a non-blocking query
+		advanced := newIndex > index
+		if newIndex < index {
+			newIndex = 1
+		}
+		if newIndex < 1 {
+			newIndex = 1
+		}
+
+		p.mu.Lock()
+		changed := !bytes.Equal(content, p.content)
+		p.index = newIndex
+		if changed {
+			p.content = content
+		}
+		p.mu.Unlock()
+
+		if changed {
+			helper.log.Infow("consul config changed", "key", p.Key, "index", newIndex)
+			p.ChangeListener(p.Datacenter, "", p.Key, string(content))
+		}
+		// a server omitting or resetting X-Consul-Index answers immediately, don't spin on it
+		if wait := consulQueryInterval - time.Since(start); !advanced && wait > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+			}
+		}
+	}
+}
+
+// read the key or prefix, a non-zero index makes it a blocking query
+func (p *ConsulProvider) read(ctx context.Context, helper *providerHelper, index uint64) ([]byte, uint64, error) {
+	addr := valueOrEnv(p.Addr, EnvConsulHTTPAddr)
+	if addr == "" {
+		addr = DefaultConsulAddr
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+
+	query := url.Values{}
+	if p.Datacenter != "" {
+		query.Set("dc", p.Datacenter)
+	}
+	if p.Prefix {
+		query.Set("recurse", "true")
+	}
+	if index > 0 {
+		wait := p.WaitTime
+		if wait <= 0 {
+			wait = DefaultConsulWaitTime
+		}
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", fmt.Sprintf("%ds", int(wait.Seconds())))
+	}
+	u := strings.TrimSuffix(addr, "/") + "/v1/kv/" + strings.TrimPrefix(p.Key, "/") + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if token := valueOrEnv(p.Token, EnvConsulHTTPToken); token != "" {
+		req.Header.Set("X-Consul-Token", token)
+	}
+	resp, err := p.httpClient().Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	newIndex, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, newIndex, fmt.Errorf("key not found, err=%w", ErrEmptyConfig)
+	default:
+		return nil, newIndex, fmt.Errorf("unexpected status %v, body=%s", resp.Status, body)
+	}
+
+	var pairs []consulKVPair
+	if err := json.Unmarshal(body, &pairs); err != nil {
+		return nil, newIndex, fmt.Errorf("decode consul response failed, err=%w", err)
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	var docs [][]byte
+	for _, pair := range pairs {
+		// skip the folder keys
+		if len(pair.Value) == 0 {
+			continue
+		}
+		value, err := helper.interpolateDocument(pair.Value)
+		if err != nil {
+			return nil, newIndex, fmt.Errorf("interpolate key %v failed, err=%w", pair.Key, err)
+		}
+		docs = append(docs, value)
+	}
+	if len(docs) == 0 {
+		return nil, newIndex, ErrEmptyConfig
+	}
+	content, err := mergeDocuments(helper, docs)
+	if err != nil {
+		return nil, newIndex, err
+	}
+	return content, newIndex, nil
+}
+
+func (p *ConsulProvider) httpClient() *http.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.client != nil:
+	case p.HTTPClient != nil:
+		p.client = p.HTTPClient
+	case p.TLSConfig != nil:
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = p.TLSConfig
+		p.client = &http.Client{Transport: transport}
+	default:
+		p.client = http.DefaultClient
+	}
+	return p.client
+}
+
+var _ changeWatcher = &ConsulProvider{}
+
+func (p *ConsulProvider) addChangeListener(listener ChangeListener) {
+	p.ChangeListener = chainListener(p.ChangeListener, listener)
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultDirPattern is the glob pattern of the fragments read by DirProvider
+const DefaultDirPattern = "*.toml"
+
+var ErrEmptyConfigDir = errors.New("error empty config dir")
+
+// DirProvider read every fragment matching Pattern in a conf.d style directory in lexical order and merge them,
+// the later fragment wins, e.g. 10-base.toml, 50-mysql.toml, 99-local.toml.
+// the directory is the --config flag if it is a directory, or DefaultConfigDir
+type DirProvider struct {
+	DefaultConfigDir      string
+	Pattern               string // default *.toml
+	SkipIfPathEmpty       bool   // skip this provider if config dir path is empty
+	SkipIfDefaultNotExist bool
+
+	// with ChangeListener set, the fragments are polled every WatchInterval (default 5s)
+	// and ChangeListener("", "", dir, content) is called on change
+	WatchInterval  time.Duration
+	ChangeListener ChangeListener
+
+	poller filePoller
+}
+
+var _ Provider = &DirProvider{}
+
+func (p *DirProvider) Name() string {
+	return "dir"
+}
+
+func (p *DirProvider) Config(helper *providerHelper) ([]byte, error) {
+	configDir := helper.configFile
+	usingDefault := false
+	if configDir != "" {
+		// the --config flag points to a file, leave it to FileProvider
+		if info, err := os.Stat(configDir); err == nil && !info.IsDir() {
+			return nil, fmt.Errorf("%w config_file=%v is not a directory", ErrSkipProvider, configDir)
+		}
+	} else {
+		configDir = p.DefaultConfigDir
+		usingDefault = true
+	}
+
+	if configDir == "" {
+		if p.SkipIfPathEmpty {
+			return nil, fmt.Errorf("%w config_file=%v default_config_dir=%v", ErrSkipProvider, helper.configFile, p.DefaultConfigDir)
+		}
+		return nil, ErrEmptyConfigDir
+	}
+
+	if _, err := os.Stat(configDir); os.IsNotExist(err) && usingDefault && p.SkipIfDefaultNotExist {
+		return nil, fmt.Errorf("default config dir not exists, %w config_file=%v default_config_dir=%v", ErrSkipProvider, helper.configFile, p.DefaultConfigDir)
+	}
+
+	content, files, err := p.read(helper, configDir)
+	if err != nil {
+		return nil, err
+	}
+	helper.log.Infow("read config from local dir success", "config_dir", configDir, "files", files)
+
+	if p.ChangeListener != nil {
+		p.watch(helper, configDir, content, files)
+	}
+	return content, nil
+}
+
+func (p *DirProvider) pattern() string {
+	if p.Pattern == "" {
+		return DefaultDirPattern
+	}
+	return p.Pattern
+}
+
+func (p *DirProvider) files(configDir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(configDir, p.pattern()))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (p *DirProvider) read(helper *providerHelper, configDir string) ([]byte, []string, error) {
+	files, err := p.files(configDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list config dir failed, dir=%v err=%w", configDir, err)
+	}
+	var docs [][]byte
+	watched := append([]string{}, files...)
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read config fragment failed, file=%v err=%w", f, err)
+		}
+		if len(bytes.TrimSpace(content)) == 0 {
+			continue
+		}
+		if err := verifyFile(helper, f, content); err != nil {
+			return nil, nil, err
+		}
+		if content, err = helper.interpolateDocument(content); err != nil {
+			return nil, nil, fmt.Errorf("interpolate config fragment failed, file=%v err=%w", f, err)
+		}
+		content, included, err := resolveIncludes(helper, f, content)
+		if err != nil {
+			return nil, nil, err
+		}
+		watched = append(watched, included[1:]...)
+		docs = append(docs, content)
+	}
+	if len(docs) == 0 {
+		return nil, nil, fmt.Errorf("read config from local dir failed, dir=%v pattern=%v err=%w", configDir, p.pattern(), ErrEmptyConfig)
+	}
+	content, err := mergeDocuments(helper, docs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("merge config fragments failed, dir=%v err=%w", configDir, err)
+	}
+	return content, watched, nil
+}
+
+func (p *DirProvider) watch(helper *providerHelper, configDir string, content []byte, watched []string) {
+	last := content
+	// the glob is listed again on each poll so new fragments are picked up, the included files are appended
+	var mu sync.Mutex
+	included := watched
+	files := func() []string {
+		files, _ := p.files(configDir)
+		mu.Lock()
+		defer mu.Unlock()
+		return append(files, included...)
+	}
+	p.poller.start(p.WatchInterval, files, func() {
+		merged, watched, err := p.read(helper, configDir)
+		if err != nil {
+			helper.log.Warnw("read changed config dir failed", "config_dir", configDir, "err", err)
+			return
+		}
+		mu.Lock()
+		included = watched
+		mu.Unlock()
+		if bytes.Equal(merged, last) {
+			return
+		}
+		last = merged
+		helper.log.Infow("config dir changed", "config_dir", configDir)
+		p.ChangeListener("", "", configDir, string(merged))
+	})
+}
+
+// Close stop the dir watching
+func (p *DirProvider) Close() {
+	p.poller.close()
+}
+
+var (
+	_ fileVerifier         = &DirProvider{}
+	_ documentInterpolator = &DirProvider{}
+)
+
+// verifiesFiles each fragment is verified against its own signature like conf.d/10-db.toml.minisig
+func (p *DirProvider) verifiesFiles() {}
+
+// interpolatesDocuments each fragment is interpolated before merging
+func (p *DirProvider) interpolatesDocuments() {}
+
+var _ changeWatcher = &DirProvider{}
+
+func (p *DirProvider) addChangeListener(listener ChangeListener) {
+	p.ChangeListener = chainListener(p.ChangeListener, listener)
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvKeySeparator separates the nested keys in env var names, e.g. APP_LOG__LEVEL is log.level
+const EnvKeySeparator = "__"
+
+// EnvProvider build the config document from the env vars with Prefix, e.g. with prefix APP_,
+// APP_METRIC_GO=true is metric_go and APP_LOG__LEVEL=debug is log.level.
+// values look like bool or number are typed, json arrays and objects are decoded, quote the value like "123456" to keep it a string
+type EnvProvider struct {
+	Prefix string
+}
+
+var _ Provider = &EnvProvider{}
+
+func (p *EnvProvider) Name() string {
+	return "env"
+}
+
+func (p *EnvProvider) Config(helper *providerHelper) ([]byte, error) {
+	if p.Prefix == "" {
+		return nil, fmt.Errorf("%w: empty env prefix", ErrSkipProvider)
+	}
+	tree := map[string]interface{}{}
+	var names []string
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, p.Prefix) || name == p.Prefix {
+			continue
+		}
+		keys := strings.Split(strings.ToLower(strings.TrimPrefix(name, p.Prefix)), EnvKeySeparator)
+		if err := setTreeValue(tree, keys, envTypedValue(value)); err != nil {
+			return nil, fmt.Errorf("env %v conflicts with another env var, err=%w", name, err)
+		}
+		names = append(names, name)
+	}
+	if len(tree) == 0 {
+		return nil, fmt.Errorf("read config from env failed, prefix=%v err=%w", p.Prefix, ErrEmptyConfig)
+	}
+	sort.Strings(names)
+	helper.log.Infow("read config from env success", "prefix", p.Prefix, "env", names)
+	return helper.encode(tree)
+}
+
+func setTreeValue(tree map[string]interface{}, keys []string, value interface{}) error {
+	for _, k := range keys[:len(keys)-1] {
+		sub, ok := tree[k].(map[string]interface{})
+		if !ok {
+			if _, exists := tree[k]; exists {
+				return fmt.Errorf("key %v is not a table", k)
+			}
+			sub = map[string]interface{}{}
+			tree[k] = sub
+		}
+		tree = sub
+	}
+	last := keys[len(keys)-1]
+	if _, ok := tree[last].(map[string]interface{}); ok {
+		return fmt.Errorf("key %v is a table", last)
+	}
+	tree[last] = value
+	return nil
+}
+
+func envTypedValue(s string) interface{} {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			// EnvMarshal quotes the json arrays and objects since they contain quotes
+			if v, ok := envJSONValue(unquoted); ok {
+				return v
+			}
+			return unquoted
+		}
+	}
+	if v, ok := envJSONValue(s); ok {
+		return v
+	}
+	if b, err := strconv.ParseBool(s); err == nil && (s == "true" || s == "false") {
+		return b
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && strings.ContainsAny(s, ".eE") {
+		return f
+	}
+	return s
+}
+
+// envJSONValue decode the json array or object, the numbers are typed like envTypedValue
+func envJSONValue(s string) (interface{}, bool) {
+	if !strings.HasPrefix(s, "[") && !strings.HasPrefix(s, "{") {
+		return nil, false
+	}
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return nil, false
+	}
+	return typeJSONNumbers(v), true
+}
+
+func typeJSONNumbers(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case json.Number:
+		return envTypedValue(vv.String())
+	case []interface{}:
+		for i := range vv {
+			vv[i] = typeJSONNumbers(vv[i])
+		}
+	case map[string]interface{}:
+		for k := range vv {
+			vv[k] = typeJSONNumbers(vv[k])
+		}
+	}
+	return v
+}
@@ -0,0 +1,491 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// EnvEtcdEndpoints etcd endpoints separated by comma
+	EnvEtcdEndpoints = "ETCD_ENDPOINTS"
+
+	DefaultEtcdEndpoint = "http://127.0.0.1:2379"
+	DefaultEtcdTimeout  = 10 * time.Second
+	// DefaultEtcdWatchIdleTimeout the watch stream is reconnected if nothing is received within it
+	DefaultEtcdWatchIdleTimeout = time.Minute
+	etcdRetryInterval           = 5 * time.Second
+)
+
+var (
+	errEtcdAuth      = errors.New("etcd auth token invalid")
+	errEtcdWatchIdle = errors.New("etcd watch idle timeout")
+)
+
+// EtcdProvider read a key or all keys under a prefix from etcd v3 via its json grpc gateway, the keys under a prefix are merged in lexical order.
+// with ChangeListener set, the key is watched from the last seen revision so no update is missed across reconnects,
+// and ChangeListener("", "", Key, content) is called on change. the watch requests progress notifications and is
+// reconnected if nothing is received within WatchIdleTimeout, so a silently dropped connection does not stall it
+type EtcdProvider struct {
+	Endpoints []string // default from env ETCD_ENDPOINTS or http://127.0.0.1:2379
+	Key       string
+	Prefix    bool
+
+	Username string
+	Password string
+
+	// TLSConfig is used as is if set, otherwise built from CAFile, CertFile and KeyFile
+	TLSConfig *tls.Config
+	CAFile    string
+	CertFile  string
+	KeyFile   string
+
+	Timeout          time.Duration // request timeout except watch, default 10s
+	WatchIdleTimeout time.Duration // default 1m
+	ChangeListener   ChangeListener
+
+	mu        sync.Mutex
+	client    *http.Client
+	token     string
+	revision  int64
+	content   []byte
+	watchOnce sync.Once
+	stop      chan struct{}
+}
+
+var (
+	_ Provider             = &EtcdProvider{}
+	_ documentInterpolator = &EtcdProvider{}
+)
+
+// interpolatesDocuments each key under the prefix is interpolated before merging
+func (p *EtcdProvider) interpolatesDocuments() {}
+
+type etcdHeader struct {
+	Revision string `json:"revision"`
+}
+
+type etcdKV struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type etcdRangeResponse struct {
+	Header etcdHeader `json:"header"`
+	Kvs    []etcdKV   `json:"kvs"`
+}
+
+type etcdWatchResponse struct {
+	Result struct {
+		Header          etcdHeader `json:"header"`
+		Created         bool       `json:"created"`
+		Canceled        bool       `json:"canceled"`
+		CompactRevision string     `json:"compact_revision"`
+		CancelReason    string     `json:"cancel_reason"`
+		Events          []struct {
+			Kv etcdKV `json:"kv"`
+		} `json:"events"`
+	} `json:"result"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (p *EtcdProvider) Name() string {
+	return "etcd"
+}
+
+func (p *EtcdProvider) Config(helper *providerHelper) ([]byte, error) {
+	if p.Key == "" {
+		return nil, fmt.Errorf("%w: empty etcd key", ErrSkipProvider)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+	content, revision, err := p.read(ctx, helper)
+	if err != nil {
+		return nil, fmt.Errorf("read config from etcd failed, key=%v err=%w", p.Key, err)
+	}
+	p.mu.Lock()
+	p.revision, p.content = revision, content
+	p.mu.Unlock()
+	helper.log.Infow("read config from etcd success", "key", p.Key, "revision", revision)
+
+	if p.ChangeListener != nil {
+		p.watchOnce.Do(func() {
+			stop := make(chan struct{})
+			p.mu.Lock()
+			p.stop = stop
+			p.mu.Unlock()
+			go p.watch(helper, stop)
+		})
+	}
+	return content, nil
+}
+
+// Close stop the watching
+func (p *EtcdProvider) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
+
+func (p *EtcdProvider) timeout() time.Duration {
+	if p.Timeout > 0 {
+		return p.Timeout
+	}
+	return DefaultEtcdTimeout
+}
+
+func (p *EtcdProvider) watchIdleTimeout() time.Duration {
+	if p.WatchIdleTimeout > 0 {
+		return p.WatchIdleTimeout
+	}
+	return DefaultEtcdWatchIdleTimeout
+}
+
+func (p *EtcdProvider) endpoints() []string {
+	endpoints := p.Endpoints
+	if len(endpoints) == 0 {
+		if env := os.Getenv(EnvEtcdEndpoints); env != "" {
+			endpoints = strings.Split(env, ",")
+		} else {
+			endpoints = []string{DefaultEtcdEndpoint}
+		}
+	}
+	out := make([]string, 0, len(endpoints))
+	for _, e := range endpoints {
+		e = strings.TrimSuffix(strings.TrimSpace(e), "/")
+		if !strings.Contains(e, "://") {
+			e = "http://" + e
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// rangeEnd returns the range end of the prefix, the key with its last byte incremented
+func (p *EtcdProvider) rangeEnd() []byte {
+	if !p.Prefix {
+		return nil
+	}
+	end := []byte(p.Key)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// all 0xff, range to the end of keyspace
+	return []byte{0}
+}
+
+// keyRange returns the key and range_end of the request body
+func (p *EtcdProvider) keyRange() map[string]interface{} {
+	r := map[string]interface{}{"key": []byte(p.Key)}
+	if end := p.rangeEnd(); end != nil {
+		r["range_end"] = end
+	}
+	return r
+}
+
+func (p *EtcdProvider) read(ctx context.Context, helper *providerHelper) ([]byte, int64, error) {
+	var resp etcdRangeResponse
+	err := p.call(ctx, "/v3/kv/range", p.keyRange(), &resp)
+	if err != nil {
+		return nil, 0, err
+	}
+	revision, _ := strconv.ParseInt(resp.Header.Revision, 10, 64)
+
+	sort.Slice(resp.Kvs, func(i, j int) bool { return bytes.Compare(resp.Kvs[i].Key, resp.Kvs[j].Key) < 0 })
+	var docs [][]byte
+	for _, kv := range resp.Kvs {
+		if len(kv.Value) == 0 {
+			continue
+		}
+		value, err := helper.interpolateDocument(kv.Value)
+		if err != nil {
+			return nil, revision, fmt.Errorf("interpolate key %s failed, err=%w", kv.Key, err)
+		}
+		docs = append(docs, value)
+	}
+	if len(docs) == 0 {
+		return nil, revision, ErrEmptyConfig
+	}
+	content, err := mergeDocuments(helper, docs)
+	return content, revision, err
+}
+
+func (p *EtcdProvider) watch(helper *providerHelper, stop chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	for ctx.Err() == nil {
+		err := p.watchOnceStream(ctx, helper)
+		if ctx.Err() != nil {
+			return
+		}
+		// nil after compaction, watch again from the latest revision
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, errEtcdWatchIdle) {
+			helper.log.Debugw("etcd watch idle, reconnecting", "key", p.Key, "timeout", p.watchIdleTimeout())
+			continue
+		}
+		helper.log.Warnw("watch etcd config interrupted, reconnecting", "key", p.Key, "err", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(etcdRetryInterval):
+		}
+	}
+}
+
+// watchOnceStream watch from the revision after the last seen one until the stream breaks or idles
+func (p *EtcdProvider) watchOnceStream(ctx context.Context, helper *providerHelper) error {
+	p.mu.Lock()
+	startRevision := p.revision + 1
+	p.mu.Unlock()
+
+	// the stream is canceled if nothing is received within the idle timeout, e.g. the connection dropped silently
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var idled int32
+	timeout := p.watchIdleTimeout()
+	idle := time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&idled, 1)
+		cancel()
+	})
+	defer idle.Stop()
+	streamErr := func(err error) error {
+		if atomic.LoadInt32(&idled) == 1 {
+			return errEtcdWatchIdle
+		}
+		return err
+	}
+
+	request := p.keyRange()
+	request["start_revision"] = strconv.FormatInt(startRevision, 10)
+	request["progress_notify"] = true
+	body := map[string]interface{}{"create_request": request}
+	resp, err := p.post(ctx, "/v3/watch", body)
+	if err != nil {
+		return streamErr(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %v, body=%s", resp.Status, msg)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var event etcdWatchResponse
+		if err := dec.Decode(&event); err != nil {
+			return streamErr(err)
+		}
+		idle.Reset(timeout)
+		if event.Error != nil {
+			return errors.New(event.Error.Message)
+		}
+		result := event.Result
+		if result.CompactRevision != "" && result.CompactRevision != "0" {
+			// the revisions we missed are compacted, reload the full content and watch from the latest revision
+			helper.log.Warnw("etcd watch revision compacted, reloading", "key", p.Key, "compact_revision", result.CompactRevision)
+			return p.refresh(ctx, helper)
+		}
+		if result.Canceled {
+			return fmt.Errorf("etcd watch canceled, reason=%v", result.CancelReason)
+		}
+		if len(result.Events) == 0 {
+			continue
+		}
+		if err := p.refresh(ctx, helper); err != nil {
+			return err
+		}
+	}
+}
+
+// refresh read the latest content, notify the listener if changed
+func (p *EtcdProvider) refresh(ctx context.Context, helper *providerHelper) error {
+	readCtx, cancel := context.WithTimeout(ctx, p.timeout())
+	defer cancel()
+	content, revision, err := p.read(readCtx, helper)
+	if err != nil && !errors.Is(err, ErrEmptyConfig) {
+		return err
+	}
+
+	p.mu.Lock()
+	changed := !bytes.Equal(content, p.content)
+	if revision > p.revision {
+		p.revision = revision
+	}
+	if changed {
+		p.content = content
+	}
+	p.mu.Unlock()
+
+	if changed {
+		helper.log.Infow("etcd config changed", "key", p.Key, "revision", revision)
+		p.ChangeListener("", "", p.Key, string(content))
+	}
+	return nil
+}
+
+// call post the request to the json gateway and decode the response
+func (p *EtcdProvider) call(ctx context.Context, path string, body, out interface{}) error {
+	resp, err := p.post(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("etcd %v failed, status=%v body=%s", path, resp.Status, data)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// post try the endpoints in order, authenticate first if username set and re-authenticate once on invalid token
+func (p *EtcdProvider) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	client, err := p.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, endpoint := range p.endpoints() {
+		for attempt := 0; attempt < 2; attempt++ {
+			var resp *http.Response
+			resp, lastErr = p.postEndpoint(ctx, client, endpoint, path, payload)
+			if errors.Is(lastErr, errEtcdAuth) {
+				p.mu.Lock()
+				p.token = ""
+				p.mu.Unlock()
+				continue
+			}
+			if lastErr == nil {
+				return resp, nil
+			}
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func (p *EtcdProvider) postEndpoint(ctx context.Context, client *http.Client, endpoint, path string, payload []byte) (*http.Response, error) {
+	token, err := p.authenticate(ctx, client, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" && resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		return nil, errEtcdAuth
+	}
+	return resp, nil
+}
+
+// authenticate returns the cached token, or request a new one if username set
+func (p *EtcdProvider) authenticate(ctx context.Context, client *http.Client, endpoint string) (string, error) {
+	if p.Username == "" {
+		return "", nil
+	}
+	p.mu.Lock()
+	token := p.token
+	p.mu.Unlock()
+	if token != "" {
+		return token, nil
+	}
+
+	payload, _ := json.Marshal(map[string]string{"name": p.Username, "password": p.Password})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/v3/auth/authenticate", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var auth struct {
+		Token string `json:"token"`
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("etcd authenticate failed, status=%v body=%s", resp.Status, data)
+	}
+	if err := json.Unmarshal(data, &auth); err != nil {
+		return "", err
+	}
+	p.mu.Lock()
+	p.token = auth.Token
+	p.mu.Unlock()
+	return auth.Token, nil
+}
+
+func (p *EtcdProvider) httpClient() (*http.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client != nil {
+		return p.client, nil
+	}
+	tlsConfig, err := buildTLSConfig(p.TLSConfig, p.CAFile, p.CertFile, p.KeyFile, false)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	// no client timeout, the watch stream is long-lived, requests are bounded by context
+	p.client = &http.Client{Transport: transport}
+	return p.client, nil
+}
+
+var _ changeWatcher = &EtcdProvider{}
+
+func (p *EtcdProvider) addChangeListener(listener ChangeListener) {
+	p.ChangeListener = chainListener(p.ChangeListener, listener)
+}
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 )
 
 var ErrEmptyConfigFile = errors.New("error empty config file")
@@ -12,6 +15,13 @@ type FileProvider struct {
 	DefaultConfigPath     string
 	SkipIfPathEmpty       bool // skip this provider if config file path is empty
 	SkipIfDefaultNotExist bool
+
+	// with ChangeListener set, the file is polled every WatchInterval (default 5s)
+	// and ChangeListener("", "", path, content) is called on change
+	WatchInterval  time.Duration
+	ChangeListener ChangeListener
+
+	poller filePoller
 }
 
 func (p *FileProvider) Name() string {
@@ -20,13 +30,16 @@ func (p *FileProvider) Name() string {
 
 var _ Provider = &FileProvider{}
 
-func (p *FileProvider) Config(helper *providerHelper) ([]byte, error) {
-	configFile := helper.configFile
-	usingDefault := false
-	if configFile == "" {
-		configFile = p.DefaultConfigPath
-		usingDefault = true
+// configPath returns the config file from flag, or the default path
+func (p *FileProvider) configPath(helper *providerHelper) (configFile string, usingDefault bool) {
+	if helper.configFile != "" {
+		return helper.configFile, false
 	}
+	return p.DefaultConfigPath, true
+}
+
+func (p *FileProvider) Config(helper *providerHelper) ([]byte, error) {
+	configFile, usingDefault := p.configPath(helper)
 
 	if configFile == "" {
 		if p.SkipIfPathEmpty {
@@ -39,13 +52,134 @@ func (p *FileProvider) Config(helper *providerHelper) ([]byte, error) {
 		return nil, fmt.Errorf("default config not exists, %w config_file=%v default_config_path=%v", ErrSkipProvider, helper.configFile, p.DefaultConfigPath)
 	}
 
+	fileContent, files, err := p.read(helper, configFile)
+	if err != nil {
+		return nil, err
+	}
+	helper.log.Infow("read config from local file success", "config_file", configFile)
+
+	if p.ChangeListener != nil {
+		p.watch(helper, configFile, fileContent, files)
+	}
+	return fileContent, nil
+}
+
+// read the config file with its includes resolved and the profile overlay like config.prod.toml merged on top,
+// files are the config file, the included files and the profile overlay. the raw content of each file is verified
+// against its signature with trusted keys configured
+func (p *FileProvider) read(helper *providerHelper, configFile string) ([]byte, []string, error) {
 	fileContent, err := os.ReadFile(configFile)
 	if err != nil {
-		return nil, fmt.Errorf("read config from local file failed, file=%v err=%w", configFile, err)
+		return nil, nil, fmt.Errorf("read config from local file failed, file=%v err=%w", configFile, err)
 	}
 	if len(fileContent) == 0 {
-		return nil, fmt.Errorf("read config from local file failed, file=%v err=%w", configFile, ErrEmptyConfig)
+		return nil, nil, fmt.Errorf("read config from local file failed, file=%v err=%w", configFile, ErrEmptyConfig)
 	}
-	helper.log.Infow("read config from local file success", "config_file", configFile)
-	return fileContent, nil
+	if err := verifyFile(helper, configFile, fileContent); err != nil {
+		return nil, nil, err
+	}
+	if fileContent, err = helper.interpolateDocument(fileContent); err != nil {
+		return nil, nil, fmt.Errorf("interpolate config file failed, file=%v err=%w", configFile, err)
+	}
+	fileContent, files, err := resolveIncludes(helper, configFile, fileContent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	overlay := profileFile(configFile, helper.profile)
+	if overlay == "" {
+		return fileContent, files, nil
+	}
+	// the overlay is watched even if it not exists yet
+	files = append(files, overlay)
+	overlayContent, err := os.ReadFile(overlay)
+	if os.IsNotExist(err) {
+		return fileContent, files, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("read profile config failed, file=%v err=%w", overlay, err)
+	}
+	if err := verifyFile(helper, overlay, overlayContent); err != nil {
+		return nil, nil, err
+	}
+	if overlayContent, err = helper.interpolateDocument(overlayContent); err != nil {
+		return nil, nil, fmt.Errorf("interpolate profile config failed, file=%v err=%w", overlay, err)
+	}
+	overlayContent, included, err := resolveIncludes(helper, overlay, overlayContent)
+	if err != nil {
+		return nil, nil, err
+	}
+	files = append(files, included[1:]...)
+	// the overlay is merged into the [profiles.<profile>] section, which the loader applies on top of the base,
+	// so the overlay file wins over the section of the base file
+	overlayTree := map[string]interface{}{}
+	if err := helper.unmarshaler(overlayContent, &overlayTree); err != nil {
+		return nil, nil, fmt.Errorf("decode profile config failed, file=%v err=%w", overlay, err)
+	}
+	section, err := helper.encode(map[string]interface{}{ProfilesKey: map[string]interface{}{helper.profile: overlayTree}})
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode profile config failed, file=%v err=%w", overlay, err)
+	}
+	fileContent, err = mergeDocuments(helper, [][]byte{fileContent, section})
+	if err != nil {
+		return nil, nil, fmt.Errorf("merge profile config failed, file=%v err=%w", overlay, err)
+	}
+	helper.log.Infow("profile config merged", "profile", helper.profile, "config_file", overlay)
+	return fileContent, files, nil
+}
+
+func (p *FileProvider) watch(helper *providerHelper, configFile string, content []byte, files []string) {
+	last := content
+	var mu sync.Mutex
+	watched := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return files
+	}
+	p.poller.start(p.WatchInterval, watched, func() {
+		fileContent, included, err := p.read(helper, configFile)
+		if err != nil {
+			helper.log.Warnw("read changed config file failed", "config_file", configFile, "err", err)
+			return
+		}
+		mu.Lock()
+		files = included
+		mu.Unlock()
+		if bytes.Equal(fileContent, last) {
+			return
+		}
+		last = fileContent
+		helper.log.Infow("config file changed", "config_file", configFile)
+		p.ChangeListener("", "", configFile, string(fileContent))
+	})
+}
+
+// Close stop the file watching
+func (p *FileProvider) Close() {
+	p.poller.close()
+}
+
+var (
+	_ SignedProvider       = &FileProvider{}
+	_ fileVerifier         = &FileProvider{}
+	_ documentInterpolator = &FileProvider{}
+)
+
+// Signature read the detached signature config.toml.minisig or config.toml.sig next to the config file.
+// with trusted keys configured, the loader verifies the config file, each included file and the profile overlay
+// against their own signatures while reading instead
+func (p *FileProvider) Signature(helper *providerHelper) ([]byte, error) {
+	configFile, _ := p.configPath(helper)
+	return readSignatureFile(configFile)
+}
+
+func (p *FileProvider) verifiesFiles() {}
+
+// interpolatesDocuments the config file, the included files and the profile overlay are interpolated before decoding
+func (p *FileProvider) interpolatesDocuments() {}
+
+var _ changeWatcher = &FileProvider{}
+
+func (p *FileProvider) addChangeListener(listener ChangeListener) {
+	p.ChangeListener = chainListener(p.ChangeListener, listener)
 }
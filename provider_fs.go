@@ -0,0 +1,94 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+const (
+	// DefaultAppEnv is the environment name if neither FSProvider.Env nor the profile is set
+	DefaultAppEnv = "default"
+	// DefaultFSPathTemplate is the default FSProvider.PathTemplate
+	DefaultFSPathTemplate = "configs/{{env}}.toml"
+)
+
+// FSProvider read the config from a fs.FS like the embed.FS of the baked-in default configs, or fstest.MapFS in tests.
+// the file is selected by PathTemplate with {{env}} replaced by the environment name, e.g.
+//
+//	//go:embed configs
+//	var configs embed.FS
+//
+//	config.WithProviders(&config.FSProvider{FS: configs}, &config.FileProvider{...})
+type FSProvider struct {
+	FS           fs.FS
+	PathTemplate string // default configs/{{env}}.toml
+	Env          string // default the active profile, or "default"
+
+	SkipIfNotExist bool // skip this provider if the selected file not exists
+}
+
+var (
+	_ Provider       = &FSProvider{}
+	_ SignedProvider = &FSProvider{}
+)
+
+func (p *FSProvider) Name() string {
+	return "fs"
+}
+
+func (p *FSProvider) env(helper *providerHelper) string {
+	if p.Env != "" {
+		return p.Env
+	}
+	if helper.profile != "" {
+		return helper.profile
+	}
+	return DefaultAppEnv
+}
+
+// path returns the file selected by the environment name
+func (p *FSProvider) path(helper *providerHelper) string {
+	tmpl := p.PathTemplate
+	if tmpl == "" {
+		tmpl = DefaultFSPathTemplate
+	}
+	return strings.ReplaceAll(tmpl, "{{env}}", p.env(helper))
+}
+
+func (p *FSProvider) Config(helper *providerHelper) ([]byte, error) {
+	if p.FS == nil {
+		return nil, errors.New("error nil fs of FSProvider")
+	}
+	path := p.path(helper)
+	content, err := fs.ReadFile(p.FS, path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && p.SkipIfNotExist {
+			return nil, fmt.Errorf("config not exists in fs, %w path=%v", ErrSkipProvider, path)
+		}
+		return nil, fmt.Errorf("read config from fs failed, path=%v err=%w", path, err)
+	}
+	if len(content) == 0 {
+		return nil, fmt.Errorf("read config from fs failed, path=%v err=%w", path, ErrEmptyConfig)
+	}
+	helper.log.Infow("read config from fs success", "path", path)
+	return content, nil
+}
+
+// Signature read the detached signature configs/prod.toml.minisig or configs/prod.toml.sig in the fs
+func (p *FSProvider) Signature(helper *providerHelper) ([]byte, error) {
+	if p.FS == nil {
+		return nil, errors.New("error nil fs of FSProvider")
+	}
+	path := p.path(helper)
+	var err error
+	for _, ext := range []string{".minisig", ".sig"} {
+		var sig []byte
+		sig, err = fs.ReadFile(p.FS, path+ext)
+		if err == nil {
+			return sig, nil
+		}
+	}
+	return nil, fmt.Errorf("read signature of %v failed, err=%w", path, err)
+}
@@ -0,0 +1,345 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHTTPTimeout      = 10 * time.Second
+	DefaultHTTPRetries      = 3
+	DefaultHTTPRetryBackoff = 500 * time.Millisecond
+)
+
+// HTTPProvider fetch the config from an http(s) url. with PollInterval and ChangeListener set,
+// the url is polled with If-None-Match/If-Modified-Since and ChangeListener("", "", URL, content) is called on change
+type HTTPProvider struct {
+	URL    string
+	Header http.Header
+
+	BearerToken string
+	Username    string // basic auth
+	Password    string
+
+	// TLSConfig is used as is if set, otherwise built from CAFile, CertFile, KeyFile and InsecureSkipVerify
+	TLSConfig          *tls.Config
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+
+	Timeout      time.Duration // per request timeout, default 10s
+	Retries      int           // retries on network error and 5xx, default 3, negative disables retry
+	RetryBackoff time.Duration // initial backoff doubled on each retry, default 500ms
+
+	PollInterval   time.Duration
+	ChangeListener ChangeListener
+
+	mu           sync.Mutex
+	client       *http.Client
+	etag         string
+	lastModified string
+	content      []byte
+	signature    []byte // the signature of content, fetched with it when trustedKeys set
+	trustedKeys  []*PublicKey
+	pollOnce     sync.Once
+	stop         chan struct{}
+}
+
+var (
+	_ Provider       = &HTTPProvider{}
+	_ SignedProvider = &HTTPProvider{}
+)
+
+// errNotModified the cached content is still valid
+var errNotModified = errors.New("not modified")
+
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+func (p *HTTPProvider) Config(helper *providerHelper) ([]byte, error) {
+	if p.URL == "" {
+		return nil, fmt.Errorf("%w: empty http config url", ErrSkipProvider)
+	}
+	p.mu.Lock()
+	p.trustedKeys = helper.trustedKeys
+	p.mu.Unlock()
+	content, err := p.fetch(context.Background(), helper.log)
+	if errors.Is(err, errNotModified) {
+		p.mu.Lock()
+		content, err = p.content, nil
+		p.mu.Unlock()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read config from http failed, url=%v err=%w", p.URL, err)
+	}
+	if len(content) == 0 {
+		return nil, fmt.Errorf("read config from http failed, url=%v err=%w", p.URL, ErrEmptyConfig)
+	}
+	helper.log.Infow("read config from http success", "url", p.URL)
+
+	if p.PollInterval > 0 && p.ChangeListener != nil {
+		p.pollOnce.Do(func() {
+			stop := make(chan struct{})
+			p.mu.Lock()
+			p.stop = stop
+			p.mu.Unlock()
+			go p.poll(helper.log, stop)
+		})
+	}
+	return content, nil
+}
+
+// Signature returns the detached signature fetched together with the content from the url with suffix .sig
+// in the path, e.g. /config.toml.sig?env=prod
+func (p *HTTPProvider) Signature(helper *providerHelper) ([]byte, error) {
+	p.mu.Lock()
+	sig := p.signature
+	p.mu.Unlock()
+	if sig != nil {
+		return sig, nil
+	}
+	client, err := p.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	return p.fetchSignature(context.Background(), client)
+}
+
+func (p *HTTPProvider) fetchSignature(ctx context.Context, client *http.Client) ([]byte, error) {
+	sigURL, err := signatureURL(p.URL)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.do(ctx, client, sigURL, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("read signature failed, status=%v", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// signatureURL append .sig to the path of the url, the query is kept
+func signatureURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("parse http config url failed, err=%w", err)
+	}
+	u.Path += ".sig"
+	if u.RawPath != "" {
+		u.RawPath += ".sig"
+	}
+	return u.String(), nil
+}
+
+// Close stop the polling
+func (p *HTTPProvider) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
+
+func (p *HTTPProvider) poll(log Logger, stop chan struct{}) {
+	ticker := time.NewTicker(p.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		content, err := p.fetch(context.Background(), log)
+		if errors.Is(err, errNotModified) {
+			continue
+		}
+		if err != nil {
+			log.Warnw("poll http config failed", "url", p.URL, "err", err)
+			continue
+		}
+		log.Infow("http config changed", "url", p.URL)
+		p.ChangeListener("", "", p.URL, string(content))
+	}
+}
+
+// fetch the url with retries, errNotModified is returned if the content not changed since last fetch
+func (p *HTTPProvider) fetch(ctx context.Context, log Logger) ([]byte, error) {
+	client, err := p.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	retries := p.Retries
+	if retries == 0 {
+		retries = DefaultHTTPRetries
+	}
+	backoff := p.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultHTTPRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		var content []byte
+		var retryable bool
+		content, retryable, err = p.fetchOnce(ctx, client)
+		if err == nil || !retryable || attempt >= retries {
+			return content, err
+		}
+		log.Warnw("fetch http config failed, retrying", "url", p.URL, "attempt", attempt+1, "backoff", backoff, "err", err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (p *HTTPProvider) fetchOnce(ctx context.Context, client *http.Client) (content []byte, retryable bool, err error) {
+	resp, err := p.do(ctx, client, p.URL, true)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil, false, errNotModified
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return nil, true, fmt.Errorf("unexpected status %v", resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return nil, false, fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	content, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+
+	// the content and the signature are fetched as a pair and verified together, a mismatch is likely
+	// the config replaced between the two requests, so it is retried
+	p.mu.Lock()
+	trustedKeys := p.trustedKeys
+	p.mu.Unlock()
+	var sig []byte
+	if len(trustedKeys) > 0 {
+		if sig, err = p.fetchSignature(ctx, client); err != nil {
+			return nil, true, err
+		}
+		if err := VerifySignature(content, sig, trustedKeys); err != nil {
+			return nil, true, err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.etag = resp.Header.Get("ETag")
+	p.lastModified = resp.Header.Get("Last-Modified")
+	p.signature = sig
+	// servers without validators always respond 200, compare the content instead
+	if p.content != nil && bytes.Equal(p.content, content) {
+		return nil, false, errNotModified
+	}
+	p.content = content
+	return content, false, nil
+}
+
+func (p *HTTPProvider) do(ctx context.Context, client *http.Client, url string, conditional bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, values := range p.Header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	switch {
+	case p.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+p.BearerToken)
+	case p.Username != "":
+		req.SetBasicAuth(p.Username, p.Password)
+	}
+	if conditional {
+		p.mu.Lock()
+		if p.content != nil && p.etag != "" {
+			req.Header.Set("If-None-Match", p.etag)
+		}
+		if p.content != nil && p.lastModified != "" {
+			req.Header.Set("If-Modified-Since", p.lastModified)
+		}
+		p.mu.Unlock()
+	}
+	return client.Do(req)
+}
+
+func (p *HTTPProvider) httpClient() (*http.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client != nil {
+		return p.client, nil
+	}
+	tlsConfig, err := p.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	p.client = &http.Client{Transport: transport, Timeout: timeout}
+	return p.client, nil
+}
+
+func (p *HTTPProvider) tlsConfig() (*tls.Config, error) {
+	return buildTLSConfig(p.TLSConfig, p.CAFile, p.CertFile, p.KeyFile, p.InsecureSkipVerify)
+}
+
+// buildTLSConfig returns tlsConfig if not nil, otherwise build it from the ca and client cert files
+func buildTLSConfig(tlsConfig *tls.Config, caFile, certFile, keyFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	if tlsConfig != nil {
+		return tlsConfig, nil
+	}
+	// nolint: gosec
+	cfg := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+	if caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca file failed, err=%w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in ca file %v", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate failed, err=%w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+var _ changeWatcher = &HTTPProvider{}
+
+func (p *HTTPProvider) addChangeListener(listener ChangeListener) {
+	p.ChangeListener = chainListener(p.ChangeListener, listener)
+}
@@ -1,8 +1,8 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 
@@ -16,25 +16,45 @@ import (
 type ChangeListener func(namespace, group, dataId, data string)
 
 type NacosProvider struct {
+	// Host, Port, Namespace, Group and DataID override the NACOS_* env vars if not empty
+	Host      string
+	Port      string
+	Namespace string
+	Group     string
+	DataID    string
+
 	ChangeListener ChangeListener
 	NacosLogger    nacosLogger.Logger // custom logger for replacing nacos default logger
 	LogLevel       string             // log level for nacos default logger
+
+	client *NacosClient
 }
 
-var _ Provider = &NacosProvider{}
+// NacosSignatureSuffix the detached signature is read from the data id with this suffix in the same group
+const NacosSignatureSuffix = ".sig"
+
+var (
+	_ Provider       = &NacosProvider{}
+	_ SignedProvider = &NacosProvider{}
+)
 
 func (p *NacosProvider) Name() string {
 	return "nacos"
 }
 
 func (p *NacosProvider) Config(helper *providerHelper) ([]byte, error) {
-	helper.log.Infow("begin create nacos client")
-	if p.LogLevel == "" {
-		p.LogLevel = "error"
-	}
-	client, err := p.newNacosClientFromEnv(helper.log)
-	if err != nil {
-		return nil, fmt.Errorf("newNacosClientFromEnv failed, err=%w", err)
+	// the client is created once and reused on reload
+	client := p.client
+	if client == nil {
+		helper.log.Infow("begin create nacos client")
+		if p.LogLevel == "" {
+			p.LogLevel = "error"
+		}
+		var err error
+		client, err = p.newNacosClientFromEnv(helper.log)
+		if err != nil {
+			return nil, fmt.Errorf("newNacosClientFromEnv failed, err=%w", err)
+		}
 	}
 
 	helper.log.Infow("begin read config from nacos")
@@ -46,9 +66,28 @@ func (p *NacosProvider) Config(helper *providerHelper) ([]byte, error) {
 		return nil, fmt.Errorf("read config from nacos failed, err=%w", ErrEmptyConfig)
 	}
 	helper.log.Infow("read config from nacos success")
+	p.client = client
 	return []byte(nacosContent), nil
 }
 
+// Signature read the detached signature from data id with suffix .sig
+func (p *NacosProvider) Signature(helper *providerHelper) ([]byte, error) {
+	if p.client == nil {
+		return nil, errors.New("nacos client not created")
+	}
+	sig, err := p.client.client.GetConfig(vo.ConfigParam{
+		DataId: p.client.dataID + NacosSignatureSuffix,
+		Group:  p.client.group,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read signature from nacos failed, err=%w", err)
+	}
+	if sig == "" {
+		return nil, fmt.Errorf("read signature from nacos failed, data_id=%v err=%w", p.client.dataID+NacosSignatureSuffix, ErrEmptyConfig)
+	}
+	return []byte(sig), nil
+}
+
 type NacosClient struct {
 	client         config_client.IConfigClient
 	servers        []string
@@ -57,19 +96,20 @@ type NacosClient struct {
 	dataID         string
 	log            Logger
 	changeListener ChangeListener
+	listening      bool
 	logLevel       string
 	nacosLogger    nacosLogger.Logger
 }
 
 func (p *NacosProvider) newNacosClientFromEnv(log Logger) (*NacosClient, error) {
 	// NACOS_HOST NACOS_PORT NACOS_NAMESPACE NACOS_GROUP NACOS_DATAID
-	host := os.Getenv(EnvNacosHost)
-	port := os.Getenv(EnvNacosPort)
+	host := valueOrEnv(p.Host, EnvNacosHost)
+	port := valueOrEnv(p.Port, EnvNacosPort)
 	nacosServers := []string{fmt.Sprintf("%s:%s", host, port)}
 
-	namespace := os.Getenv(EnvNacosNamespace)
-	group := os.Getenv(EnvNacosGroup)
-	dataID := os.Getenv(EnvNacosDataID)
+	namespace := valueOrEnv(p.Namespace, EnvNacosNamespace)
+	group := valueOrEnv(p.Group, EnvNacosGroup)
+	dataID := valueOrEnv(p.DataID, EnvNacosDataID)
 
 	log.Debugw("reading nacos config from env", EnvNacosHost, host, EnvNacosPort, port, EnvNacosNamespace, namespace, EnvNacosGroup, group,
 		EnvNacosDataID, dataID, "nacosServers", nacosServers)
@@ -99,7 +139,7 @@ func (p *NacosProvider) newNacosClientFromEnv(log Logger) (*NacosClient, error)
 }
 
 func (n *NacosClient) readConfig() (string, error) {
-	if n.changeListener != nil {
+	if n.changeListener != nil && !n.listening {
 		n.log.Infow("begin setup nacos config change listener")
 		err := n.client.ListenConfig(vo.ConfigParam{
 			DataId:   n.dataID,
@@ -109,6 +149,7 @@ func (n *NacosClient) readConfig() (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("nacos ListenConfig failed, err=%w", err)
 		}
+		n.listening = true
 	}
 
 	n.log.Infow("begin get config via nacos api")
@@ -156,3 +197,9 @@ func (n *NacosClient) createNacosConfigClient() error {
 	n.client = nc
 	return err
 }
+
+var _ changeWatcher = &NacosProvider{}
+
+func (p *NacosProvider) addChangeListener(listener ChangeListener) {
+	p.ChangeListener = chainListener(p.ChangeListener, listener)
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// StdinProvider read the config from stdin, the content is read once and reused on reload
+type StdinProvider struct {
+	once    sync.Once
+	content []byte
+	err     error
+}
+
+var _ Provider = &StdinProvider{}
+
+func (p *StdinProvider) Name() string {
+	return "stdin"
+}
+
+func (p *StdinProvider) Config(helper *providerHelper) ([]byte, error) {
+	p.once.Do(func() {
+		p.content, p.err = io.ReadAll(os.Stdin)
+	})
+	if p.err != nil {
+		return nil, fmt.Errorf("read config from stdin failed, err=%w", p.err)
+	}
+	if len(p.content) == 0 {
+		return nil, fmt.Errorf("read config from stdin failed, err=%w", ErrEmptyConfig)
+	}
+	helper.log.Infow("read config from stdin success")
+	return p.content, nil
+}
@@ -1,10 +1,16 @@
 package config
 
+import "errors"
+
 type TextProvider struct {
-	ConfigText []byte
+	ConfigText      []byte
+	ConfigSignature []byte // detached signature of ConfigText, see WithTrustedKeys
 }
 
-var _ Provider = &TextProvider{}
+var (
+	_ Provider       = &TextProvider{}
+	_ SignedProvider = &TextProvider{}
+)
 
 func (p *TextProvider) Name() string {
 	return "text"
@@ -17,3 +23,10 @@ func (p *TextProvider) Config(helper *providerHelper) ([]byte, error) {
 	}
 	return content, nil
 }
+
+func (p *TextProvider) Signature(helper *providerHelper) ([]byte, error) {
+	if len(p.ConfigSignature) == 0 {
+		return nil, errors.New("no signature of config text")
+	}
+	return p.ConfigSignature, nil
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// RedactedValue replaces the secret values in the redacted config
+const RedactedValue = "******"
+
+// TagSecret mark a field as secret, e.g. `secret:"true"`, the value of this field and its children is always redacted
+const TagSecret = "secret"
+
+// secretKeyWords the key names containing one of these words are considered secret
+var secretKeyWords = []string{"password", "passwd", "pwd", "secret", "token", "credential", "private_key", "access_key", "api_key", "apikey"}
+
+// mysqlDsnPassword match the password part of mysql dsn like user:pass@tcp(host:3306)/db
+var mysqlDsnPassword = regexp.MustCompile(`^([^:@/]*):([^@]*)@`)
+
+// Redact convert the config into a generic tree with the secret values replaced by RedactedValue.
+// a value is secret if its field is tagged with `secret:"true"` or the key name looks like a password or token,
+// passwords inside URIs and mysql DSNs are masked too
+func Redact(cfg interface{}) (map[string]interface{}, error) {
+	return redactWith(cfg, nil)
+}
+
+// redactWith redact the key paths in extra as well, e.g. the values resolved by SecretResolver
+func redactWith(cfg interface{}, extra map[string]bool) (map[string]interface{}, error) {
+	tree, err := toTree(cfg)
+	if err != nil {
+		return nil, err
+	}
+	secrets := secretPaths(cfg)
+	for path := range extra {
+		secrets[path] = true
+	}
+	return redactTree(tree, "", secrets), nil
+}
+
+// secretPaths collect the key paths of fields tagged as secret
+func secretPaths(cfg interface{}) map[string]bool {
+	paths := map[string]bool{}
+	walkFields(reflect.ValueOf(cfg), "", func(path string, field reflect.StructField, value reflect.Value) bool {
+		if field.Tag.Get(TagSecret) == "true" {
+			paths[path] = true
+			return false
+		}
+		return true
+	})
+	return paths
+}
+
+func redactTree(tree map[string]interface{}, prefix string, secrets map[string]bool) map[string]interface{} {
+	out := make(map[string]interface{}, len(tree))
+	for k, v := range tree {
+		path := joinPath(prefix, k)
+		out[k] = redactValue(path, v, secrets)
+	}
+	return out
+}
+
+func redactValue(path string, v interface{}, secrets map[string]bool) interface{} {
+	if isSecretPath(path, secrets) {
+		return RedactedValue
+	}
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		return redactTree(vv, path, secrets)
+	case []interface{}:
+		items := make([]interface{}, len(vv))
+		for i, item := range vv {
+			items[i] = redactValue(path, item, secrets)
+		}
+		return items
+	case string:
+		return redactString(vv)
+	default:
+		return v
+	}
+}
+
+// RedactValue redact the value at the key path using the key name rules only
+func RedactValue(path string, v interface{}) interface{} {
+	return redactValue(path, v, nil)
+}
+
+func isSecretPath(path string, secrets map[string]bool) bool {
+	for p := range secrets {
+		if hasPathPrefix(path, p) {
+			return true
+		}
+	}
+	name := strings.ToLower(path[strings.LastIndex(path, ".")+1:])
+	for _, word := range secretKeyWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactString mask the password in uri like redis://:pass@host:6379 or mysql dsn like user:pass@tcp(host)/db
+func redactString(s string) string {
+	if strings.Contains(s, "://") {
+		if u, err := url.Parse(s); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				// url.String escapes "*", set a placeholder and replace it afterwards
+				u.User = url.UserPassword(u.User.Username(), "redacted")
+				return strings.Replace(u.String(), ":redacted@", ":"+RedactedValue+"@", 1)
+			}
+		}
+		return s
+	}
+	if m := mysqlDsnPassword.FindStringSubmatchIndex(s); m != nil && m[5] > m[4] {
+		return s[:m[4]] + RedactedValue + s[m[5]:]
+	}
+	return s
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var ErrNotLoaded = errors.New("config not loaded yet, call Load first")
+
+// ReloadEvent is passed to the reload listeners after a new config is swapped in,
+// Old and New are pointers of the type passed to Load and must not be modified
+type ReloadEvent struct {
+	Old    interface{}
+	New    interface{}
+	Source string // the providers of the new config, e.g. file or /etc/app.toml,env://APP_
+
+	// Changes between Old and New, e.g. event.Changes.Under("mysql") reports whether the mysql config changed
+	Changes Changes
+	// RestartRequired are the changes of the `reload:"restart"` fields, which keep the old values in New
+	RestartRequired Changes
+}
+
+type ReloadListener func(event ReloadEvent)
+
+// snapshot is an immutable loaded config
+type snapshot struct {
+	cfg        interface{}
+	source     string
+	provenance Provenance
+	hash       string // sha256 of the effective config
+	loadedAt   time.Time
+	secrets    map[string]bool // key paths of the values resolved by SecretResolver
+}
+
+// redact the config with the resolved secrets
+func (s *snapshot) redact() (map[string]interface{}, error) {
+	return redactWith(s.cfg, s.secrets)
+}
+
+// diffSnapshots diff the configs with the resolved secrets of both redacted
+func diffSnapshots(old, new *snapshot) (Changes, error) {
+	secrets := make(map[string]bool, len(old.secrets)+len(new.secrets))
+	for path := range old.secrets {
+		secrets[path] = true
+	}
+	for path := range new.secrets {
+		secrets[path] = true
+	}
+	return diffWith(old.cfg, new.cfg, secrets)
+}
+
+func (cl *ConfigLoader) current() *snapshot {
+	snap, _ := cl.snapshot.Load().(*snapshot)
+	return snap
+}
+
+// Current returns the config of the last successful Load or Reload, nil before Load.
+// it is the pointer passed to Load until the first Reload, which loads into a new copy of the defaults
+func (cl *ConfigLoader) Current() interface{} {
+	if snap := cl.current(); snap != nil {
+		return snap.cfg
+	}
+	return nil
+}
+
+// OnReload register a listener called after each successful Reload, in the order of the reloads
+func (cl *ConfigLoader) OnReload(listener ReloadListener) {
+	cl.listenerMu.Lock()
+	defer cl.listenerMu.Unlock()
+	cl.listeners = append(cl.listeners, listener)
+}
+
+// Reload read the providers again into a copy of the defaults passed to Load, the inspect function is run
+// and the new config is swapped in only if it passes, the current config is kept on any error.
+// after the swap the reload health checks are run, and the previous config is restored if any of them fails,
+// the listeners are notified only after the checks pass. the flags are not parsed again, reloads are serialized
+func (cl *ConfigLoader) Reload() error {
+	cl.reloadMu.Lock()
+	defer cl.reloadMu.Unlock()
+
+	old := cl.current()
+	if old == nil || cl.defaults == nil {
+		return ErrNotLoaded
+	}
+	changes, err := cl.reload(old)
+	cl.reloadState.record(changes, err)
+	return err
+}
+
+func (cl *ConfigLoader) reload(old *snapshot) (Changes, error) {
+	cl.options.logger.Infow("begin reload config")
+
+	cfg, err := cloneConfig(cl.defaults)
+	if err != nil {
+		return nil, fmt.Errorf("copy config defaults failed, err=%w", err)
+	}
+	// the telemetry started by Setup on the current config keeps running
+	carryTelemetry(old.cfg, cfg)
+	snap, err := cl.loadConfig(cfg)
+	if err != nil {
+		cl.metrics.reloaded(ResultFailure)
+		cl.options.logger.Errorw("reload config failed, keep the current config", "err", err)
+		return nil, fmt.Errorf("reload config failed, err=%w", err)
+	}
+	restart, err := cl.applyReloadPolicy(old, snap)
+	if err != nil {
+		cl.metrics.reloaded(ResultRejected)
+		cl.options.logger.Errorw("reloaded config rejected by reload policy, keep the current config", "err", err)
+		return nil, fmt.Errorf("reload config rejected, err=%w", err)
+	}
+	if len(restart) > 0 {
+		if snap.hash, err = configHash(cfg); err != nil {
+			return nil, fmt.Errorf("hash config failed, err=%w", err)
+		}
+	}
+	if cl.options.inspectConfig != nil {
+		if err := cl.options.inspectConfig(cfg); err != nil {
+			cl.metrics.reloaded(ResultRejected)
+			cl.options.logger.Errorw("reloaded config rejected by inspect, keep the current config", "err", err)
+			return nil, fmt.Errorf("inspect reloaded config failed with error: %w", err)
+		}
+	}
+
+	// the values are redacted
+	changes, err := diffSnapshots(old, snap)
+	if err != nil {
+		cl.options.logger.Warnw("diff reloaded config failed", "err", err)
+	}
+
+	// apply the new config, then roll back if the health checks fail
+	cl.snapshot.Store(snap)
+	event := ReloadEvent{Old: old.cfg, New: snap.cfg, Source: snap.source, Changes: changes, RestartRequired: restart}
+	if err := cl.checkReloadHealth(event); err != nil {
+		cl.rollback(old, snap, err)
+		return nil, fmt.Errorf("%w: %v", ErrReloadRolledBack, err)
+	}
+	cl.notifyReload(event)
+
+	cl.pushHistory(old)
+	cl.metrics.reloaded(ResultSuccess)
+	cl.metrics.loaded(snap)
+	cl.options.logger.Infow("config reloaded successfully", "provider", snap.source, "sha256", snap.hash, "changes", changes.Strings())
+	if len(restart) > 0 {
+		cl.restartRequired(restart)
+	}
+	return changes, nil
+}
+
+func (cl *ConfigLoader) notifyReload(event ReloadEvent) {
+	cl.listenerMu.Lock()
+	listeners := append([]ReloadListener{}, cl.listeners...)
+	cl.listenerMu.Unlock()
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// cloneConfig deep copy the pointer to config struct, the unexported fields are copied shallowly
+func cloneConfig(cfg interface{}) (interface{}, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, fmt.Errorf("config must be a non-nil pointer, got %T", cfg)
+	}
+	return deepCopy(v).Interface(), nil
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"syscall"
+)
+
+// TagReload set the reload policy of a field and its children, e.g. `reload:"restart"`, untagged fields are hot
+const TagReload = "reload"
+
+type ReloadPolicy string
+
+const (
+	// ReloadHot the new value is applied on reload
+	ReloadHot ReloadPolicy = "hot"
+	// ReloadRestart the old value is kept until the process restarts, the change is reported
+	// via the config_restart_required metric, the log and WithRestartRequired
+	ReloadRestart ReloadPolicy = "restart"
+	// ReloadImmutable a reload changing the field is rejected
+	ReloadImmutable ReloadPolicy = "immutable"
+)
+
+var ErrImmutableChanged = errors.New("immutable config changed")
+
+// RestartRequiredHandler is called with the restart-required changes of a successful reload
+type RestartRequiredHandler func(changes Changes)
+
+// reloadPolicies collect the key paths of the fields tagged with a reload policy
+func reloadPolicies(cfg interface{}) (map[string]ReloadPolicy, error) {
+	policies := map[string]ReloadPolicy{}
+	var err error
+	walkFields(reflect.ValueOf(cfg), "", func(path string, field reflect.StructField, value reflect.Value) bool {
+		tag, ok := field.Tag.Lookup(TagReload)
+		if !ok {
+			return true
+		}
+		switch policy := ReloadPolicy(strings.TrimSpace(tag)); policy {
+		case ReloadHot:
+			return true
+		case ReloadRestart, ReloadImmutable:
+			policies[path] = policy
+			return false
+		default:
+			err = fmt.Errorf("invalid reload tag %q of %v, must be one of hot|restart|immutable", tag, path)
+			return false
+		}
+	})
+	return policies, err
+}
+
+// policyChanges split the changes by the policy of the tagged field they are under
+func policyChanges(changes Changes, policies map[string]ReloadPolicy) (restart, immutable Changes) {
+	for _, c := range changes {
+		for path, policy := range policies {
+			if !hasPathPrefix(c.Path, path) {
+				continue
+			}
+			if policy == ReloadImmutable {
+				immutable = append(immutable, c)
+			} else {
+				restart = append(restart, c)
+			}
+			break
+		}
+	}
+	return restart, immutable
+}
+
+// keepRestartFields copy the tagged restart fields having changes from old into cfg
+func keepRestartFields(old, cfg interface{}, changes Changes, policies map[string]ReloadPolicy) {
+	paths := map[string]bool{}
+	for path, policy := range policies {
+		if policy == ReloadRestart && changes.Under(path) {
+			paths[path] = true
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+	oldValues := map[string]reflect.Value{}
+	walkFields(reflect.ValueOf(old), "", func(path string, field reflect.StructField, value reflect.Value) bool {
+		if paths[path] {
+			oldValues[path] = value
+			return false
+		}
+		return true
+	})
+	walkFields(reflect.ValueOf(cfg), "", func(path string, field reflect.StructField, value reflect.Value) bool {
+		if !paths[path] {
+			return true
+		}
+		if ov, ok := oldValues[path]; ok && value.CanSet() {
+			value.Set(deepCopy(ov))
+		}
+		return false
+	})
+}
+
+// applyReloadPolicy reject the reload changing immutable fields, and keep the old values of the restart fields in snap.
+// returns the restart-required changes
+func (cl *ConfigLoader) applyReloadPolicy(old, snap *snapshot) (Changes, error) {
+	policies, err := reloadPolicies(snap.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	changes, err := diffSnapshots(old, snap)
+	if err != nil {
+		return nil, fmt.Errorf("diff reloaded config failed, err=%w", err)
+	}
+	restart, immutable := policyChanges(changes, policies)
+	if len(immutable) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrImmutableChanged, strings.Join(immutable.Strings(), ", "))
+	}
+	keepRestartFields(old.cfg, snap.cfg, restart, policies)
+	return restart, nil
+}
+
+// restartRequired report the restart-required changes, and send SIGTERM to the process with WithShutdownOnRestartRequired
+func (cl *ConfigLoader) restartRequired(changes Changes) {
+	cl.options.logger.Warnw("config changes require restart, the old values are kept", "changes", changes.Strings())
+	cl.metrics.restartRequired()
+	if cl.options.restartRequiredHandler != nil {
+		cl.options.restartRequiredHandler(changes)
+	}
+	if cl.options.shutdownOnRestartRequired {
+		cl.options.logger.Warnw("shutting down for config changes requiring restart", "keys", changes.Paths())
+		p, err := os.FindProcess(os.Getpid())
+		if err == nil {
+			err = p.Signal(syscall.SIGTERM)
+		}
+		if err != nil {
+			cl.options.logger.Errorw("send SIGTERM for restart failed", "err", err)
+		}
+	}
+}
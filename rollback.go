@@ -0,0 +1,106 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultReloadHealthCheckTimeout is the deadline of all the reload health checks
+	DefaultReloadHealthCheckTimeout = 10 * time.Second
+	// DefaultSnapshotHistory is the number of the previous configs retained
+	DefaultSnapshotHistory = 5
+)
+
+var ErrReloadRolledBack = errors.New("reload rolled back")
+
+// ReloadHealthCheck verify the service works with the reloaded config, e.g. ping the db with the new dsn.
+// it is called after the new config is swapped in and before the OnReload listeners, event.New is the current config
+type ReloadHealthCheck func(ctx context.Context, event ReloadEvent) error
+
+// AddReloadHealthCheck register a health check run after each reload, the reload is rolled back if any check fails
+// or the checks exceed the deadline of WithReloadHealthCheckTimeout
+func (cl *ConfigLoader) AddReloadHealthCheck(check ReloadHealthCheck) {
+	cl.listenerMu.Lock()
+	defer cl.listenerMu.Unlock()
+	cl.healthChecks = append(cl.healthChecks, check)
+}
+
+func (cl *ConfigLoader) checkReloadHealth(event ReloadEvent) error {
+	cl.listenerMu.Lock()
+	checks := append([]ReloadHealthCheck{}, cl.healthChecks...)
+	cl.listenerMu.Unlock()
+	if len(checks) == 0 {
+		return nil
+	}
+
+	timeout := cl.options.reloadHealthCheckTimeout
+	if timeout <= 0 {
+		timeout = DefaultReloadHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for i, check := range checks {
+		done := make(chan error, 1)
+		go func() {
+			done <- check(ctx, event)
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				return fmt.Errorf("reload health check %d failed, err=%w", i, err)
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("reload health check %d failed, err=%w", i, ctx.Err())
+		}
+	}
+	return nil
+}
+
+// rollback restore the previous config, the listeners never saw the failed one
+func (cl *ConfigLoader) rollback(old, failed *snapshot, cause error) {
+	cl.options.logger.Errorw("reload health check failed, roll back to the previous config",
+		"provider", old.source, "sha256", old.hash, "failed_sha256", failed.hash, "err", cause)
+	cl.snapshot.Store(old)
+	cl.metrics.reloaded(ResultRolledBack)
+}
+
+// SnapshotInfo describe a previously active config
+type SnapshotInfo struct {
+	Config   interface{} `json:"-"` // the config, must not be modified
+	Source   string      `json:"source"`
+	SHA256   string      `json:"sha256"`
+	LoadedAt time.Time   `json:"loaded_at"`
+}
+
+// pushHistory retain the replaced snapshot, the oldest one is dropped beyond WithSnapshotHistory
+func (cl *ConfigLoader) pushHistory(snap *snapshot) {
+	limit := cl.options.snapshotHistory
+	if limit == 0 {
+		limit = DefaultSnapshotHistory
+	}
+	if limit < 0 {
+		return
+	}
+	cl.historyMu.Lock()
+	defer cl.historyMu.Unlock()
+	cl.history = append(cl.history, snap)
+	if len(cl.history) > limit {
+		cl.history = append([]*snapshot{}, cl.history[len(cl.history)-limit:]...)
+	}
+}
+
+// History returns the previously active configs, the newest first
+func (cl *ConfigLoader) History() []SnapshotInfo {
+	cl.historyMu.Lock()
+	defer cl.historyMu.Unlock()
+	infos := make([]SnapshotInfo, 0, len(cl.history))
+	for i := len(cl.history) - 1; i >= 0; i-- {
+		snap := cl.history[i]
+		infos = append(infos, SnapshotInfo{Config: snap.cfg, Source: snap.source, SHA256: snap.hash, LoadedAt: snap.loadedAt})
+	}
+	return infos
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SourceSecret is the provenance source of the values resolved by SecretResolver
+const SourceSecret = "secret"
+
+// SecretRefPrefix optionally mark a string value as secret reference, e.g. secret:vault://kv/data/mysql#password,
+// which fails the load if no resolver is registered for the scheme instead of keeping the value as is
+const SecretRefPrefix = "secret:"
+
+const (
+	EnvVaultAddr      = "VAULT_ADDR"
+	EnvVaultToken     = "VAULT_TOKEN"
+	EnvVaultNamespace = "VAULT_NAMESPACE"
+
+	DefaultSecretResolveTimeout = 30 * time.Second
+)
+
+// SecretResolver resolve the secret references in string fields after unmarshalling,
+// e.g. with a resolver of scheme "vault" registered, the value vault://kv/data/mysql#password is replaced by the secret.
+// the key paths of the resolved values are redacted like the fields tagged with `secret:"true"`
+type SecretResolver interface {
+	// Scheme returns the uri scheme handled by this resolver
+	Scheme() string
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// secretResolution resolve the secret references of one load, the same reference is resolved only once.
+// a new resolution is used for each load so secrets are re-resolved on reload
+type secretResolution struct {
+	resolvers map[string]SecretResolver
+	cache     map[string]string
+	resolved  map[string]bool // key paths of the resolved values
+}
+
+func newSecretResolution(resolvers []SecretResolver) *secretResolution {
+	r := &secretResolution{
+		resolvers: make(map[string]SecretResolver, len(resolvers)),
+		cache:     map[string]string{},
+		resolved:  map[string]bool{},
+	}
+	for _, resolver := range resolvers {
+		r.resolvers[resolver.Scheme()] = resolver
+	}
+	return r
+}
+
+// resolve replace the secret references in the string and []string fields of cfg
+func (r *secretResolution) resolve(ctx context.Context, cfg interface{}) error {
+	var err error
+	walkFields(reflect.ValueOf(cfg), "", func(path string, field reflect.StructField, value reflect.Value) bool {
+		if err != nil {
+			return false
+		}
+		switch {
+		case value.Kind() == reflect.String && value.CanSet():
+			err = r.resolveValue(ctx, path, value)
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < value.Len() && err == nil; i++ {
+				err = r.resolveValue(ctx, path, value.Index(i))
+			}
+		}
+		return true
+	})
+	return err
+}
+
+// resolveValue resolve the reference of a registered scheme like vault://kv/data/mysql#password,
+// values of other schemes are kept unless marked with SecretRefPrefix
+func (r *secretResolution) resolveValue(ctx context.Context, path string, value reflect.Value) error {
+	ref, marked := strings.CutPrefix(value.String(), SecretRefPrefix)
+	scheme, _, ok := strings.Cut(ref, "://")
+	if !ok {
+		if marked {
+			return fmt.Errorf("invalid secret reference for key %v, want %vscheme://...", path, SecretRefPrefix)
+		}
+		return nil
+	}
+	resolver, ok := r.resolvers[scheme]
+	if !ok {
+		if marked {
+			return fmt.Errorf("no secret resolver for key %v, scheme=%v", path, scheme)
+		}
+		return nil
+	}
+	secret, ok := r.cache[ref]
+	if !ok {
+		var err error
+		secret, err = resolver.Resolve(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("resolve secret for key %v failed, scheme=%v err=%w", path, scheme, err)
+		}
+		r.cache[ref] = secret
+	}
+	value.SetString(secret)
+	r.resolved[path] = true
+	return nil
+}
+
+// FileSecretResolver resolve file:///run/secrets/name references to the file content, for docker and kubernetes secrets.
+// a relative path like file://secrets/name is relative to Dir, or to the working directory if Dir is empty
+type FileSecretResolver struct {
+	// Dir restricts the secret files inside this directory if not empty
+	Dir string
+}
+
+var _ SecretResolver = &FileSecretResolver{}
+
+func (r *FileSecretResolver) Scheme() string {
+	return "file"
+}
+
+func (r *FileSecretResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	// not parsed as url, the first element of a relative path would be the host
+	ref = strings.TrimPrefix(ref, "file://")
+	ref, _, _ = strings.Cut(ref, "#")
+	if ref == "" {
+		return "", errors.New("empty secret file path")
+	}
+	path := filepath.FromSlash(ref)
+	if !filepath.IsAbs(path) && r.Dir != "" {
+		path = filepath.Join(r.Dir, path)
+	}
+	path = filepath.Clean(path)
+	if r.Dir != "" {
+		rel, err := filepath.Rel(filepath.Clean(r.Dir), path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("secret file %v is outside of %v", ref, r.Dir)
+		}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// VaultSecretResolver resolve vault://kv/data/mysql#password references via the vault http api,
+// both KV v2 (data.data.password) and KV v1 (data.password) responses are supported
+type VaultSecretResolver struct {
+	Addr       string // default from env VAULT_ADDR
+	Token      string // default from env VAULT_TOKEN
+	Namespace  string // default from env VAULT_NAMESPACE
+	HTTPClient *http.Client
+}
+
+var _ SecretResolver = &VaultSecretResolver{}
+
+func (r *VaultSecretResolver) Scheme() string {
+	return "vault"
+}
+
+func (r *VaultSecretResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	secretPath, key, _ := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(ref, SecretRefPrefix), "vault://"), "#")
+	if secretPath == "" {
+		return "", fmt.Errorf("invalid vault reference %q", ref)
+	}
+	addr := valueOrEnv(r.Addr, EnvVaultAddr)
+	if addr == "" {
+		return "", fmt.Errorf("vault address is empty, set %v", EnvVaultAddr)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(secretPath, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	if token := valueOrEnv(r.Token, EnvVaultToken); token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace := valueOrEnv(r.Namespace, EnvVaultNamespace); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault read %v failed, status=%v body=%s", secretPath, resp.Status, body)
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("decode vault response failed, err=%w", err)
+	}
+	data := secret.Data
+	if kv2, ok := data["data"].(map[string]interface{}); ok {
+		data = kv2
+	}
+	if key == "" {
+		if len(data) != 1 {
+			return "", errors.New("vault reference without #key must point to a secret with exactly one key")
+		}
+		for k := range data {
+			key = k
+		}
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in vault secret %v", key, secretPath)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+func valueOrEnv(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
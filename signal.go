@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchReloadSignal reload the config on the signals until Close
+func (cl *ConfigLoader) watchReloadSignal() {
+	sigs := cl.options.reloadSignals
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	stop := make(chan struct{})
+	cl.closers = append(cl.closers, func() {
+		signal.Stop(ch)
+		close(stop)
+	})
+	cl.options.logger.Infow("reload config on signal", "signals", sigs)
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case sig := <-ch:
+				cl.options.logger.Infow("reload config on signal received", "signal", sig.String())
+				// the error is logged and recorded in ReloadStatus
+				_ = cl.Reload()
+			}
+		}
+	}()
+}
+
+// Close stop the reload signal handler and the watching of the providers
+func (cl *ConfigLoader) Close() {
+	cl.closeOnce.Do(func() {
+		for _, closer := range cl.closers {
+			closer()
+		}
+		for _, source := range cl.sources {
+			if c, ok := source.provider.(interface{ Close() }); ok {
+				c.Close()
+			}
+		}
+	})
+}
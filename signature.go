@@ -0,0 +1,237 @@
+package config
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// EnvTrustedKeys public keys trusted for config signature verification, separated by comma
+const EnvTrustedKeys = "CONFIG_TRUSTED_KEYS"
+
+// ErrSignatureVerification is returned when the provider content is not signed by a trusted key,
+// the loading is aborted instead of trying the next provider
+var ErrSignatureVerification = errors.New("config signature verification failed")
+
+const (
+	// signature algorithms of minisign, Ed signs the content, ED signs the blake2b-512 hash of the content
+	sigAlgEd         = "Ed"
+	sigAlgEdPrehash  = "ED"
+	sigKeyIDSize     = 8
+	untrustedComment = "untrusted comment: "
+	trustedComment   = "trusted comment: "
+)
+
+// SignedProvider is implemented by providers able to fetch the detached signature of their content,
+// e.g. FileProvider reads config.toml.minisig or config.toml.sig next to config.toml
+type SignedProvider interface {
+	Signature(helper *providerHelper) ([]byte, error)
+}
+
+// fileVerifier is implemented by the providers verifying the raw content of each local file they read,
+// e.g. FileProvider with includes and profile overlay, so the merged content is not verified again
+type fileVerifier interface {
+	verifiesFiles()
+}
+
+// verifyFile verify the raw content of a local config file against the detached signature next to it,
+// no-op if no trusted key configured
+func verifyFile(helper *providerHelper, path string, content []byte) error {
+	if len(helper.trustedKeys) == 0 {
+		return nil
+	}
+	sig, err := readSignatureFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSignatureVerification, err)
+	}
+	if err := VerifySignature(content, sig, helper.trustedKeys); err != nil {
+		return fmt.Errorf("file=%v err=%w", path, err)
+	}
+	helper.log.Infow("config file signature verified", "file", path)
+	return nil
+}
+
+// readSignatureFile read the detached signature config.toml.minisig or config.toml.sig of the file
+func readSignatureFile(path string) ([]byte, error) {
+	var err error
+	for _, ext := range []string{".minisig", ".sig"} {
+		var sig []byte
+		sig, err = os.ReadFile(path + ext)
+		if err == nil {
+			return sig, nil
+		}
+	}
+	return nil, fmt.Errorf("read signature of %v failed, err=%w", path, err)
+}
+
+// PublicKey is a trusted ed25519 key for config signature verification
+type PublicKey struct {
+	keyID []byte // nil for raw ed25519 keys
+	key   ed25519.PublicKey
+}
+
+// ParsePublicKey parse a minisign public key (optionally with its untrusted comment line) or a raw base64 ed25519 public key
+func ParsePublicKey(s string) (*PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(lastLine(s))
+	if err != nil {
+		return nil, fmt.Errorf("decode public key failed, err=%w", err)
+	}
+	switch len(raw) {
+	case ed25519.PublicKeySize:
+		return &PublicKey{key: raw}, nil
+	case 2 + sigKeyIDSize + ed25519.PublicKeySize:
+		if string(raw[:2]) != sigAlgEd {
+			return nil, fmt.Errorf("unsupported public key algorithm %q", raw[:2])
+		}
+		return &PublicKey{keyID: raw[2 : 2+sigKeyIDSize], key: raw[2+sigKeyIDSize:]}, nil
+	default:
+		return nil, fmt.Errorf("invalid public key size %d", len(raw))
+	}
+}
+
+// ParseTrustedKeys parse the keys, the keys in env CONFIG_TRUSTED_KEYS are appended
+func ParseTrustedKeys(keys []string) ([]*PublicKey, error) {
+	if env := os.Getenv(EnvTrustedKeys); env != "" {
+		keys = append(keys, strings.Split(env, ",")...)
+	}
+	var parsed []*PublicKey
+	for _, k := range keys {
+		if strings.TrimSpace(k) == "" {
+			continue
+		}
+		key, err := ParsePublicKey(k)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, key)
+	}
+	return parsed, nil
+}
+
+type signature struct {
+	alg       string
+	keyID     []byte
+	sig       []byte
+	comment   string // trusted comment
+	globalSig []byte
+}
+
+// parseSignature parse a minisign signature file or a raw base64 ed25519 signature
+func parseSignature(content []byte) (*signature, error) {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 1 {
+		raw, err := base64.StdEncoding.DecodeString(lines[0])
+		if err != nil {
+			return nil, fmt.Errorf("decode signature failed, err=%w", err)
+		}
+		if len(raw) != ed25519.SignatureSize {
+			return nil, fmt.Errorf("invalid signature size %d", len(raw))
+		}
+		return &signature{alg: sigAlgEd, sig: raw}, nil
+	}
+
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], untrustedComment) || !strings.HasPrefix(lines[2], trustedComment) {
+		return nil, errors.New("invalid minisign signature format")
+	}
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(raw) != 2+sigKeyIDSize+ed25519.SignatureSize {
+		return nil, errors.New("invalid minisign signature")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return nil, errors.New("invalid minisign global signature")
+	}
+	return &signature{
+		alg:       string(raw[:2]),
+		keyID:     raw[2 : 2+sigKeyIDSize],
+		sig:       raw[2+sigKeyIDSize:],
+		comment:   strings.TrimPrefix(lines[2], trustedComment),
+		globalSig: globalSig,
+	}, nil
+}
+
+// VerifySignature verify the detached signature of content against the trusted keys
+func VerifySignature(content, sig []byte, trustedKeys []*PublicKey) error {
+	s, err := parseSignature(sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSignatureVerification, err)
+	}
+	message := content
+	switch s.alg {
+	case sigAlgEd:
+	case sigAlgEdPrehash:
+		hash := blake2b.Sum512(content)
+		message = hash[:]
+	default:
+		return fmt.Errorf("%w: unsupported signature algorithm %q", ErrSignatureVerification, s.alg)
+	}
+
+	for _, key := range trustedKeys {
+		if s.keyID != nil && key.keyID != nil && !bytes.Equal(s.keyID, key.keyID) {
+			continue
+		}
+		if !ed25519.Verify(key.key, message, s.sig) {
+			continue
+		}
+		if s.globalSig != nil && !ed25519.Verify(key.key, append(append([]byte{}, s.sig...), s.comment...), s.globalSig) {
+			return fmt.Errorf("%w: invalid trusted comment signature", ErrSignatureVerification)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: not signed by any trusted key", ErrSignatureVerification)
+}
+
+// GenerateSigningKey returns a minisign compatible public key and the private key for SignConfig
+func GenerateSigningKey() (publicKeyText, privateKeyText string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	keyID := make([]byte, sigKeyIDSize)
+	if _, err := rand.Read(keyID); err != nil {
+		return "", "", err
+	}
+	enc := base64.StdEncoding.EncodeToString
+	pubRaw := append(append([]byte(sigAlgEd), keyID...), pub...)
+	privRaw := append(append([]byte(sigAlgEd), keyID...), priv...)
+	publicKeyText = fmt.Sprintf("%sconfig public key %X\n%s\n", untrustedComment, keyID, enc(pubRaw))
+	privateKeyText = fmt.Sprintf("%sconfig secret key %X, unencrypted, not compatible with minisign\n%s\n", untrustedComment, keyID, enc(privRaw))
+	return publicKeyText, privateKeyText, nil
+}
+
+// SignConfig sign the content with the private key from GenerateSigningKey, the output is a minisign signature file
+func SignConfig(content []byte, privateKeyText, comment string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(lastLine(privateKeyText))
+	if err != nil || len(raw) != 2+sigKeyIDSize+ed25519.PrivateKeySize || string(raw[:2]) != sigAlgEd {
+		return nil, errors.New("invalid private key")
+	}
+	keyID, priv := raw[2:2+sigKeyIDSize], ed25519.PrivateKey(raw[2+sigKeyIDSize:])
+
+	hash := blake2b.Sum512(content)
+	sig := ed25519.Sign(priv, hash[:])
+	comment = strings.ReplaceAll(comment, "\n", " ")
+	globalSig := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+
+	enc := base64.StdEncoding.EncodeToString
+	text := fmt.Sprintf("%ssignature from config secret key %X\n%s\n%s%s\n%s\n",
+		untrustedComment, keyID, enc(append(append([]byte(sigAlgEdPrehash), keyID...), sig...)), trustedComment, comment, enc(globalSig))
+	return []byte(text), nil
+}
+
+// lastLine returns the last non empty line, skipping the minisign comment line
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
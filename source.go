@@ -0,0 +1,178 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SourceStdin is the config source reading from stdin
+const SourceStdin = "-"
+
+// ProviderFactory create a provider from a config source uri like nacos://host:8848/namespace/group/dataId
+type ProviderFactory func(u *url.URL) (Provider, error)
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = map[string]ProviderFactory{}
+)
+
+// RegisterScheme register the provider factory of the uri scheme for the --config flag, the existing one is replaced
+func RegisterScheme(scheme string, factory ProviderFactory) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	schemes[strings.ToLower(scheme)] = factory
+}
+
+func init() {
+	RegisterScheme("file", fileSource)
+	RegisterScheme("nacos", nacosSource)
+	RegisterScheme("http", httpSource)
+	RegisterScheme("https", httpSource)
+	RegisterScheme("consul", consulSource)
+	RegisterScheme("etcd", etcdSource)
+	RegisterScheme("env", envSource)
+}
+
+// isURISource report whether the --config value is an uri or stdin instead of a plain file path
+func isURISource(source string) bool {
+	return source == SourceStdin || strings.Contains(source, "://")
+}
+
+// ProviderFromURI create the provider of the config source, a plain path is a file, "-" is stdin
+func ProviderFromURI(source string) (Provider, error) {
+	if source == SourceStdin {
+		return &StdinProvider{}, nil
+	}
+	if !isURISource(source) {
+		return fileSource(&url.URL{Scheme: "file", Path: source})
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config source %q, err=%w", source, err)
+	}
+	schemesMu.RLock()
+	factory, ok := schemes[strings.ToLower(u.Scheme)]
+	schemesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported config source scheme %q", u.Scheme)
+	}
+	return factory(u)
+}
+
+// fileSource file:///etc/app/config.toml, file://relative/config.toml or a directory for DirProvider
+func fileSource(u *url.URL) (Provider, error) {
+	path := u.Host + u.Path
+	if path == "" {
+		path = u.Opaque
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return &DirProvider{DefaultConfigDir: path, Pattern: u.Query().Get("pattern")}, nil
+	}
+	return &FileProvider{DefaultConfigPath: path}, nil
+}
+
+// nacosSource nacos://host:8848/namespace/group/dataId?log_level=error
+func nacosSource(u *url.URL) (Provider, error) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid nacos source %v, must be nacos://host:port/namespace/group/dataId", u.Redacted())
+	}
+	return &NacosProvider{
+		Host:      u.Hostname(),
+		Port:      u.Port(),
+		Namespace: parts[0],
+		Group:     parts[1],
+		DataID:    parts[2],
+		LogLevel:  u.Query().Get("log_level"),
+	}, nil
+}
+
+// httpSource the url is used as is, the user info is used as basic auth
+func httpSource(u *url.URL) (Provider, error) {
+	p := &HTTPProvider{}
+	if u.User != nil {
+		p.Username = u.User.Username()
+		p.Password, _ = u.User.Password()
+		u.User = nil
+	}
+	p.URL = u.String()
+	return p, nil
+}
+
+// consulSource consul://host:8500/key/path?dc=dc1&prefix=true&token=xxx&scheme=https
+func consulSource(u *url.URL) (Provider, error) {
+	q := u.Query()
+	scheme := q.Get("scheme")
+	if scheme == "" {
+		scheme = "http"
+	}
+	prefix, err := queryBool(q, "prefix")
+	if err != nil {
+		return nil, err
+	}
+	p := &ConsulProvider{
+		Key:        strings.TrimPrefix(u.Path, "/"),
+		Datacenter: q.Get("dc"),
+		Token:      q.Get("token"),
+		Prefix:     prefix,
+	}
+	if u.Host != "" {
+		p.Addr = scheme + "://" + u.Host
+	}
+	return p, nil
+}
+
+// etcdSource etcd://host:2379/key/path?prefix=true&scheme=https, multiple endpoints separated by comma in host
+func etcdSource(u *url.URL) (Provider, error) {
+	q := u.Query()
+	scheme := q.Get("scheme")
+	if scheme == "" {
+		scheme = "http"
+	}
+	prefix, err := queryBool(q, "prefix")
+	if err != nil {
+		return nil, err
+	}
+	p := &EtcdProvider{Key: strings.TrimPrefix(u.Path, "/"), Prefix: prefix}
+	if u.User != nil {
+		p.Username = u.User.Username()
+		p.Password, _ = u.User.Password()
+	}
+	for _, host := range strings.Split(u.Host, ",") {
+		if host != "" {
+			p.Endpoints = append(p.Endpoints, scheme+"://"+host)
+		}
+	}
+	if timeout := q.Get("timeout"); timeout != "" {
+		if p.Timeout, err = time.ParseDuration(timeout); err != nil {
+			return nil, fmt.Errorf("invalid etcd timeout %q, err=%w", timeout, err)
+		}
+	}
+	return p, nil
+}
+
+// envSource env://APP_
+func envSource(u *url.URL) (Provider, error) {
+	prefix := u.Host + u.Path
+	if prefix == "" {
+		prefix = u.Opaque
+	}
+	return &EnvProvider{Prefix: prefix}, nil
+}
+
+func queryBool(q url.Values, key string) (bool, error) {
+	v := q.Get(key)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %v=%q, err=%w", key, v, err)
+	}
+	return b, nil
+}
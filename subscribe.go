@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ChangeHandler is called with the old and new values of the key path, which are copies of the struct fields
+type ChangeHandler func(old, new interface{})
+
+// OnChange register a handler called after a successful Reload changed any key under the path, e.g.
+//
+//	loader.OnChange("log.level", func(old, new interface{}) { level.SetLevel(...) })
+//
+// the path follows the toml keys like Diff, empty path is the whole config.
+// values of the fields tagged `reload:"restart"` are kept on reload so they never trigger the handler
+func (cl *ConfigLoader) OnChange(path string, handler ChangeHandler) {
+	cl.OnReload(func(event ReloadEvent) {
+		if !event.Changes.Under(path) {
+			return
+		}
+		old, _ := valueAtPath(event.Old, path)
+		new, _ := valueAtPath(event.New, path)
+		handler(interfaceOf(old), interfaceOf(new))
+	})
+}
+
+// Subscribe register a typed handler like OnChange, V must be the type of the field at the path, e.g.
+//
+//	config.Subscribe(loader, "ratelimit.qps", func(old, new int) { limiter.SetLimit(rate.Limit(new)) })
+//
+// the path and the type are checked against the current config if loaded
+func Subscribe[V any](cl *ConfigLoader, path string, handler func(old, new V)) error {
+	want := reflect.TypeOf((*V)(nil)).Elem()
+	if cfg := cl.Current(); cfg != nil {
+		v, ok := valueAtPath(cfg, path)
+		if !ok {
+			return fmt.Errorf("subscribe %q failed, no such key in config", path)
+		}
+		if !v.Type().AssignableTo(want) {
+			return fmt.Errorf("subscribe %q failed, the key is %v instead of %v", path, v.Type(), want)
+		}
+	}
+	cl.OnChange(path, func(old, new interface{}) {
+		o, _ := old.(V)
+		n, ok := new.(V)
+		if !ok {
+			cl.options.logger.Errorw("config change not delivered, type mismatch", "path", path, "type", fmt.Sprintf("%T", new), "want", want.String())
+			return
+		}
+		handler(o, n)
+	})
+	return nil
+}
+
+// valueAtPath returns the field of the config at the toml key path, or the config itself for empty path
+func valueAtPath(cfg interface{}, path string) (reflect.Value, bool) {
+	v := reflect.ValueOf(cfg)
+	if path == "" {
+		return v, v.IsValid()
+	}
+	var found reflect.Value
+	walkFields(v, "", func(p string, field reflect.StructField, value reflect.Value) bool {
+		if found.IsValid() {
+			return false
+		}
+		if p == path {
+			found = value
+			return false
+		}
+		return hasPathPrefix(path, p)
+	})
+	return found, found.IsValid()
+}
+
+// interfaceOf returns a deep copy of the value, nil for invalid value
+func interfaceOf(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	return deepCopy(v).Interface()
+}
@@ -0,0 +1,253 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+
+	"github.com/kk-kwok/config/version"
+)
+
+// ShutdownFunc flush and stop what Setup started
+type ShutdownFunc func(ctx context.Context) error
+
+type setupOptions struct {
+	logger   Logger
+	registry *prometheus.Registry
+	handlers map[string]http.Handler
+	sampler  sdktrace.Sampler
+}
+
+type SetupOption interface {
+	apply(*setupOptions)
+}
+
+type setupOptionFunc func(*setupOptions)
+
+func (f setupOptionFunc) apply(o *setupOptions) {
+	f(o)
+}
+
+// WithSetupLogger set the logger of the telemetry bootstrap, default is nop
+func WithSetupLogger(opt Logger) SetupOption {
+	return setupOptionFunc(func(o *setupOptions) {
+		o.logger = opt
+	})
+}
+
+// WithSetupRegistry register the collectors to the given registry instead of a new one
+func WithSetupRegistry(opt *prometheus.Registry) SetupOption {
+	return setupOptionFunc(func(o *setupOptions) {
+		o.registry = opt
+	})
+}
+
+// WithAdminHandler mount an extra handler on the admin listener,
+// e.g. WithAdminHandler("/admin/", http.StripPrefix("/admin", loader.AdminHandler()))
+func WithAdminHandler(pattern string, handler http.Handler) SetupOption {
+	return setupOptionFunc(func(o *setupOptions) {
+		if o.handlers == nil {
+			o.handlers = map[string]http.Handler{}
+		}
+		o.handlers[pattern] = handler
+	})
+}
+
+// WithTraceSampler set the sampler of the tracer provider, default is parent based always sample
+func WithTraceSampler(opt sdktrace.Sampler) SetupOption {
+	return setupOptionFunc(func(o *setupOptions) {
+		o.sampler = opt
+	})
+}
+
+// MetricsRegistry returns the prometheus registry created by Setup, nil before Setup or with Metric disabled.
+// the registry is kept by the config reloaded by ConfigLoader.Reload
+func (b *Base) MetricsRegistry() *prometheus.Registry {
+	return b.registry
+}
+
+// telemetryHolder is implemented by Base, for copying the state of Setup to the reloaded config
+type telemetryHolder interface {
+	MetricsRegistry() *prometheus.Registry
+	setMetricsRegistry(registry *prometheus.Registry)
+}
+
+func (b *Base) setMetricsRegistry(registry *prometheus.Registry) {
+	b.registry = registry
+}
+
+// carryTelemetry copy the registry of Setup from the old config to the new one,
+// the tracer provider is global and needs no copy
+func carryTelemetry(old, cfg interface{}) {
+	from, ok := old.(telemetryHolder)
+	if !ok {
+		return
+	}
+	if to, ok := cfg.(telemetryHolder); ok {
+		to.setMetricsRegistry(from.MetricsRegistry())
+	}
+}
+
+// Setup bootstrap the telemetry according to the flags of Base:
+//   - Metric: a prometheus registry with the build info, and the go and process collectors with MetricGo and MetricProcess
+//   - Tracing: an opentelemetry tracer provider exporting to OtlpGrpcEndpoint, set as the global one
+//   - AdminAddr: an http listener exposing /metrics with Metric and /debug/pprof/ with Profile
+//
+// the returned ShutdownFunc stops the listener and flushes the spans, call it before the app exits
+func (b *Base) Setup(ctx context.Context, opts ...SetupOption) (ShutdownFunc, error) {
+	o := setupOptions{logger: zap.NewNop().Sugar()}
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	var shutdowns []ShutdownFunc
+	shutdown := func(ctx context.Context) error {
+		var errs []error
+		// stop in the reverse order of starting
+		for i := len(shutdowns) - 1; i >= 0; i-- {
+			if err := shutdowns[i](ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	if b.Metric {
+		if err := b.setupMetrics(o.registry); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.Tracing {
+		tp, err := b.setupTracing(ctx, o.sampler)
+		if err != nil {
+			return nil, err
+		}
+		shutdowns = append(shutdowns, tp.Shutdown)
+		o.logger.Infow("opentelemetry tracing enabled", "otlp_grpc_endpoint", b.OtlpGrpcEndpoint)
+	}
+
+	if b.AdminAddr != "" {
+		srvShutdown, err := b.serveAdmin(o)
+		if err != nil {
+			_ = shutdown(ctx)
+			return nil, err
+		}
+		shutdowns = append(shutdowns, srvShutdown)
+	}
+	return shutdown, nil
+}
+
+func (b *Base) setupMetrics(registry *prometheus.Registry) error {
+	if registry == nil {
+		registry = prometheus.NewRegistry()
+	}
+	cs := []prometheus.Collector{version.NewCollector(metricNamespace(version.ServiceName))}
+	if b.MetricGo {
+		cs = append(cs, collectors.NewGoCollector())
+	}
+	if b.MetricProcess {
+		cs = append(cs, collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+	for _, c := range cs {
+		if err := registry.Register(c); err != nil {
+			return fmt.Errorf("register prometheus collector failed, err=%w", err)
+		}
+	}
+	b.registry = registry
+	return nil
+}
+
+// metricNamespace replace the chars invalid in metric names, e.g. user-api is user_api
+func metricNamespace(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// setupTracing the endpoint is host:port or http(s)://host:port, plaintext is used unless the scheme is https.
+// empty endpoint leaves it to the OTEL_EXPORTER_OTLP_* env vars
+func (b *Base) setupTracing(ctx context.Context, sampler sdktrace.Sampler) (*sdktrace.TracerProvider, error) {
+	var opts []otlptracegrpc.Option
+	if endpoint := b.OtlpGrpcEndpoint; endpoint != "" {
+		secure := strings.HasPrefix(endpoint, "https://")
+		endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
+		opts = append(opts, otlptracegrpc.WithEndpoint(endpoint))
+		if !secure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create otlp trace exporter failed, err=%w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", version.ServiceName),
+		attribute.String("service.version", version.Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("create otel resource failed, err=%w", err)
+	}
+	if sampler == nil {
+		sampler = sdktrace.ParentBased(sdktrace.AlwaysSample())
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp, nil
+}
+
+func (b *Base) serveAdmin(o setupOptions) (ShutdownFunc, error) {
+	mux := http.NewServeMux()
+	if b.registry != nil {
+		mux.Handle("/metrics", promhttp.HandlerFor(b.registry, promhttp.HandlerOpts{Registry: b.registry}))
+	}
+	if b.Profile {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	for pattern, handler := range o.handlers {
+		mux.Handle(pattern, handler)
+	}
+
+	// listen synchronously so a port conflict fails the setup
+	ln, err := net.Listen("tcp", b.AdminAddr)
+	if err != nil {
+		return nil, fmt.Errorf("listen admin addr failed, addr=%v err=%w", b.AdminAddr, err)
+	}
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			o.logger.Errorw("admin server stopped", "addr", b.AdminAddr, "err", err)
+		}
+	}()
+	o.logger.Infow("admin server started", "addr", ln.Addr().String(), "metrics", b.registry != nil, "pprof", b.Profile)
+	return srv.Shutdown, nil
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+type adminConfig struct {
+	config.Base
+	Name     string `toml:"name"`
+	Password string `toml:"password"`
+	DSN      string `toml:"dsn" secret:"true"`
+}
+
+func serveAdmin(handler http.Handler, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminAuth(t *testing.T) {
+	file := writeFile(t, filepath.Join(t.TempDir(), "config.toml"), "name = \"v1\"\n")
+	loader := newFileLoader(file, config.WithLogger(&captureLogger{}), config.WithAdminToken("s3cret"))
+	if err := loader.Load(&adminConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	handler := loader.AdminHandler()
+	for _, path := range []string{"/config", "/provenance", "/version", "/reload", "/history"} {
+		for _, token := range []string{"", "wrong"} {
+			rec := serveAdmin(handler, http.MethodGet, path, token)
+			if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+				t.Fatalf("GET %v with token %q = %v", path, token, rec.Code)
+			}
+		}
+		if rec := serveAdmin(handler, http.MethodGet, path, "s3cret"); rec.Code != http.StatusOK {
+			t.Fatalf("GET %v = %v %s", path, rec.Code, rec.Body)
+		}
+	}
+	if rec := serveAdmin(handler, http.MethodPost, "/reload", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("POST /reload without token = %v", rec.Code)
+	}
+
+	t.Setenv(config.EnvAdminToken, "from-env")
+	handler = newFileLoader(file).AdminHandler()
+	if rec := serveAdmin(handler, http.MethodGet, "/version", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("GET /version without the env token = %v", rec.Code)
+	}
+	if rec := serveAdmin(handler, http.MethodGet, "/version", "from-env"); rec.Code != http.StatusOK {
+		t.Fatalf("GET /version with the env token = %v", rec.Code)
+	}
+}
+
+func TestAdminConfigRedacted(t *testing.T) {
+	file := writeFile(t, filepath.Join(t.TempDir(), "config.toml"),
+		"name = \"v1\"\npassword = \"hunter2\"\ndsn = \"mysql://root:pw@db\"\n")
+	loader := newFileLoader(file, config.WithLogger(&captureLogger{}))
+	handler := loader.AdminHandler()
+	if rec := serveAdmin(handler, http.MethodGet, "/config", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("GET /config before load = %v", rec.Code)
+	}
+	if err := loader.Load(&adminConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+
+	for _, format := range []string{"toml", "yaml", "json", "env"} {
+		rec := serveAdmin(handler, http.MethodGet, "/config?format="+format, "")
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(body, "v1") || !strings.Contains(body, config.RedactedValue) {
+			t.Fatalf("GET /config?format=%v = %v %s", format, rec.Code, body)
+		}
+		if strings.Contains(body, "hunter2") || strings.Contains(body, "root:pw") {
+			t.Fatalf("secret in GET /config?format=%v: %s", format, body)
+		}
+		if rec.Header().Get("X-Config-Sha256") != loader.ReloadStatus().SHA256 {
+			t.Fatalf("X-Config-Sha256 = %q", rec.Header().Get("X-Config-Sha256"))
+		}
+	}
+	if rec := serveAdmin(handler, http.MethodGet, "/config?format=xml", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("GET /config?format=xml = %v", rec.Code)
+	}
+	if rec := serveAdmin(handler, http.MethodPost, "/config", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST /config = %v", rec.Code)
+	}
+}
+
+func TestAdminReload(t *testing.T) {
+	file := writeFile(t, filepath.Join(t.TempDir(), "config.toml"), "name = \"v1\"\npassword = \"old-pw\"\n")
+	loader := newFileLoader(file, config.WithLogger(&captureLogger{}))
+	handler := loader.AdminHandler()
+	if rec := serveAdmin(handler, http.MethodPost, "/reload", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("POST /reload before load = %v", rec.Code)
+	}
+	if err := loader.Load(&adminConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+
+	status := func(rec *httptest.ResponseRecorder) config.ReloadStatus {
+		t.Helper()
+		var s config.ReloadStatus
+		if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil {
+			t.Fatalf("decode reload status %s failed, err=%v", rec.Body, err)
+		}
+		return s
+	}
+
+	writeFile(t, file, "name = \"v2\"\npassword = \"new-pw\"\n")
+	rec := serveAdmin(handler, http.MethodPost, "/reload", "")
+	s := status(rec)
+	if rec.Code != http.StatusOK || s.Reloads != 1 || s.LastError != "" || len(s.LastChanges) != 2 {
+		t.Fatalf("POST /reload = %v %+v", rec.Code, s)
+	}
+	if strings.Contains(rec.Body.String(), "-pw") {
+		t.Fatalf("secret in the reload changes: %s", rec.Body)
+	}
+	if got := loader.Current().(*adminConfig).Name; got != "v2" {
+		t.Fatalf("name = %q after POST /reload", got)
+	}
+
+	writeFile(t, file, "name = \n")
+	rec = serveAdmin(handler, http.MethodPost, "/reload", "")
+	s = status(rec)
+	if rec.Code != http.StatusInternalServerError || s.Failures != 1 || s.LastError == "" {
+		t.Fatalf("POST /reload of invalid config = %v %+v", rec.Code, s)
+	}
+
+	rec = serveAdmin(handler, http.MethodGet, "/reload", "")
+	if s = status(rec); rec.Code != http.StatusOK || s.Reloads != 1 || s.Failures != 1 || s.LastError == "" {
+		t.Fatalf("GET /reload = %v %+v", rec.Code, s)
+	}
+	if rec = serveAdmin(handler, http.MethodGet, "/history", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "sha256") {
+		t.Fatalf("GET /history = %v %s", rec.Code, rec.Body)
+	}
+	if rec = serveAdmin(handler, http.MethodDelete, "/reload", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("DELETE /reload = %v", rec.Code)
+	}
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+type diffConfig struct {
+	config.Base
+	Name  string `toml:"name"`
+	MySQL struct {
+		DSN      string `toml:"dsn" secret:"true"`
+		Password string `toml:"password"`
+		Timeout  int    `toml:"timeout"`
+	} `toml:"mysql"`
+}
+
+func TestDiffRedacted(t *testing.T) {
+	old, new := &diffConfig{Name: "v1"}, &diffConfig{Name: "v1"}
+	old.MySQL.DSN, new.MySQL.DSN = "root:old@db", "root:new@db"
+	old.MySQL.Password, new.MySQL.Password = "old-pw", "new-pw"
+	new.MySQL.Timeout = 3
+
+	changes, err := config.Diff(old, new)
+	if err != nil {
+		t.Fatalf("diff failed, err=%v", err)
+	}
+	if want := []string{"mysql.dsn", "mysql.password", "mysql.timeout"}; !reflect.DeepEqual(changes.Paths(), want) {
+		t.Fatalf("paths = %v, want %v", changes.Paths(), want)
+	}
+	for _, c := range changes[:2] {
+		if c.Type != config.ChangeModified || c.Old != config.RedactedValue || c.New != config.RedactedValue {
+			t.Fatalf("secret change not redacted: %+v", c)
+		}
+	}
+	if line := changes[2].String(); line != "~ mysql.timeout: 0 -> 3" {
+		t.Fatalf("timeout change = %q", line)
+	}
+	if !changes.Under("mysql") || changes.Under("name") || changes.Under("mysql.dsn.user") {
+		t.Fatalf("Under mismatched, changes=%v", changes.Strings())
+	}
+
+	if changes, err = config.Diff(old, old); err != nil || len(changes) != 0 {
+		t.Fatalf("diff of the same config = %v, err=%v", changes, err)
+	}
+}
+
+func TestDiffDocuments(t *testing.T) {
+	old := []byte("name = \"v1\"\nremoved = 1\n[mysql]\npassword = \"old-pw\"\n")
+	new := []byte("name = \"v2\"\nadded = true\n[mysql]\npassword = \"new-pw\"\n")
+	changes, err := config.DiffDocuments(old, new, config.TomlUnmarshaler)
+	if err != nil {
+		t.Fatalf("diff failed, err=%v", err)
+	}
+	want := []string{
+		"+ added = true",
+		"~ mysql.password: " + config.RedactedValue + " -> " + config.RedactedValue,
+		"~ name: v1 -> v2",
+		"- removed = 1",
+	}
+	if !reflect.DeepEqual(changes.Strings(), want) {
+		t.Fatalf("changes = %q, want %q", changes.Strings(), want)
+	}
+	if _, err := config.DiffDocuments(old, []byte("name = \n"), config.TomlUnmarshaler); err == nil {
+		t.Fatal("invalid document not reported")
+	}
+}
+
+func TestConfigtoolDiffExitCode(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	dir := t.TempDir()
+	tool := filepath.Join(dir, "configtool")
+	if out, err := exec.Command("go", "build", "-o", tool, "github.com/kk-kwok/config/cmd/configtool").CombinedOutput(); err != nil {
+		t.Fatalf("build configtool failed, err=%v\n%s", err, out)
+	}
+	v1 := writeFile(t, filepath.Join(dir, "v1.toml"), "name = \"v1\"\npassword = \"old-pw\"\n")
+	v2 := writeFile(t, filepath.Join(dir, "v2.toml"), "name = \"v2\"\npassword = \"new-pw\"\n")
+
+	cases := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"same", []string{v1, v1}, 0},
+		{"differ", []string{v1, v2}, 1},
+		{"missing file", []string{v1, filepath.Join(dir, "missing.toml")}, 2},
+		{"usage", []string{v1}, 2},
+	}
+	for _, c := range cases {
+		out, err := exec.Command(tool, append([]string{"diff"}, c.args...)...).Output()
+		code := 0
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			code = exit.ExitCode()
+		} else if err != nil {
+			t.Fatalf("%v: run configtool failed, err=%v", c.name, err)
+		}
+		if code != c.code {
+			t.Fatalf("%v: exit code = %v, want %v", c.name, code, c.code)
+		}
+		if c.code == 1 && (!strings.Contains(string(out), "~ name: v1 -> v2") || strings.Contains(string(out), "-pw")) {
+			t.Fatalf("%v: output = %s", c.name, out)
+		}
+	}
+}
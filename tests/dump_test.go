@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+// dumpFlags is the flag result of --dump with --dump-mode, --dump-provenance and --profile
+type dumpFlags struct {
+	flagResult
+	mode    string
+	profile string
+}
+
+func (f dumpFlags) DumpConfig() bool     { return true }
+func (f dumpFlags) DumpMode() string     { return f.mode }
+func (f dumpFlags) DumpProvenance() bool { return true }
+func (f dumpFlags) Profile() string      { return f.profile }
+
+type dumpConfig struct {
+	config.Base
+	Name     string `toml:"name"`
+	Password string `toml:"password"`
+}
+
+func dump(t *testing.T, flags dumpFlags) string {
+	t.Helper()
+	var err error
+	out := captureOutput(t, &os.Stdout, func() {
+		loader := config.New(
+			config.WithProviders(&config.FileProvider{}),
+			config.WithFlagParser(func() config.FlagParseResult { return flags }),
+			config.WithLogger(&captureLogger{}),
+		)
+		captureOutput(t, &os.Stderr, func() {
+			err = loader.Load(&dumpConfig{Name: "default"})
+		})
+	})
+	if !errors.Is(err, config.ErrConfigDumped) {
+		t.Fatalf("dump err = %v, want ErrConfigDumped", err)
+	}
+	return out
+}
+
+func TestDumpDemo(t *testing.T) {
+	file := writeFile(t, filepath.Join(t.TempDir(), "config.toml"), "name = \"file\"\n")
+	t.Setenv(config.EnvOtlpGrpcEndpoint, "otel:4317")
+
+	out := dump(t, dumpFlags{flagResult: flagResult{configFile: file}, mode: "demo", profile: "prod"})
+	if !strings.Contains(out, "# dump mode: demo") || !strings.Contains(out, `name = 'default'`) {
+		t.Fatalf("demo dump:\n%s", out)
+	}
+	if strings.Contains(out, `'file'`) || strings.Contains(out, "otel:4317") || strings.Contains(out, "provenance") {
+		t.Fatalf("demo dump not the template:\n%s", out)
+	}
+	// the providers are not consulted
+	out = dump(t, dumpFlags{flagResult: flagResult{configFile: filepath.Join(t.TempDir(), "missing.toml")}, mode: "demo"})
+	if !strings.Contains(out, `name = 'default'`) {
+		t.Fatalf("demo dump of missing config file:\n%s", out)
+	}
+}
+
+func TestDumpEffective(t *testing.T) {
+	key := newKey(t)
+	t.Setenv(config.EnvEncryptionKey, base64.StdEncoding.EncodeToString(key))
+	t.Setenv(config.EnvOtlpGrpcEndpoint, "otel:4317")
+	file := writeFile(t, filepath.Join(t.TempDir(), "config.toml"),
+		"name = \"base\"\npassword = \""+encrypt(t, key, "hunter2")+"\"\n[profiles.prod]\nname = \"prod\"\n")
+
+	out := dump(t, dumpFlags{flagResult: flagResult{configFile: file}, mode: "effective", profile: "prod"})
+	for _, want := range []string{
+		"# dump mode: effective",
+		`name = 'prod'`,
+		`otlp_grpc_endpoint = 'otel:4317'`,
+		`password = '` + config.RedactedValue + `'`,
+		"#   otlp_grpc_endpoint = " + config.SourceEnv,
+		"#   tracing = " + config.SourceDefault,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("effective dump missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "ENC[") {
+		t.Fatalf("decrypted value in the effective dump:\n%s", out)
+	}
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kk-kwok/config"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+func encrypt(t *testing.T, key []byte, plaintext string) string {
+	t.Helper()
+	value, err := config.EncryptValue([]byte(plaintext), key, config.EncryptedTypeString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+	encoded, err := config.GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := base64.StdEncoding.DecodeString(encoded)
+	return key
+}
+
+func TestDecryptValuesQuoting(t *testing.T) {
+	key := newKey(t)
+	plaintext := `p"a\ss: #word`
+	enc := encrypt(t, key, plaintext)
+
+	tomlContent := "basic = \"" + enc + "\"\nliteral = '" + encrypt(t, key, `C:\path`) + "'\n"
+	got, err := config.DecryptValues([]byte(tomlContent), [][]byte{key})
+	if err != nil {
+		t.Fatalf("decrypt toml failed, err=%v", err)
+	}
+	var tomlDoc map[string]string
+	if err := toml.Unmarshal(got, &tomlDoc); err != nil {
+		t.Fatalf("unmarshal %q failed, err=%v", got, err)
+	}
+	if tomlDoc["basic"] != plaintext || tomlDoc["literal"] != `C:\path` {
+		t.Fatalf("toml values %q", tomlDoc)
+	}
+
+	yamlContent := "plain: " + enc + "\ndouble: \"" + enc + "\"\n"
+	got, err = config.DecryptValues([]byte(yamlContent), [][]byte{key})
+	if err != nil {
+		t.Fatalf("decrypt yaml failed, err=%v", err)
+	}
+	var yamlDoc map[string]string
+	if err := yaml.Unmarshal(got, &yamlDoc); err != nil {
+		t.Fatalf("unmarshal %q failed, err=%v", got, err)
+	}
+	if yamlDoc["plain"] != plaintext || yamlDoc["double"] != plaintext {
+		t.Fatalf("yaml values %q", yamlDoc)
+	}
+}
+
+func TestDecryptValuesWithoutKey(t *testing.T) {
+	content := "# old = \"" + encrypt(t, newKey(t), "x") + "\"\nnote = \"ENC[ is not encrypted\"\n"
+	got, err := config.DecryptValues([]byte(content), nil)
+	if err != nil {
+		t.Fatalf("decrypt failed, err=%v", err)
+	}
+	if string(got) != content {
+		t.Fatalf("content changed, got %q", got)
+	}
+	if _, err := config.DecryptValues([]byte("pass = \""+encrypt(t, newKey(t), "x")+"\"\n"), nil); err == nil {
+		t.Fatal("want error for encrypted value without key")
+	}
+}
+
+type encryptedConfig struct {
+	config.Base
+	Password string `toml:"password"`
+	Home     string `toml:"home"`
+}
+
+func TestDecryptedNotInterpolated(t *testing.T) {
+	key := newKey(t)
+	t.Setenv(config.EnvEncryptionKey, base64.StdEncoding.EncodeToString(key))
+	t.Setenv("INTERPOLATE_HOME", "/home/app")
+	content := "password = \"" + encrypt(t, key, "pa${HOME}ss") + "\"\nhome = \"${INTERPOLATE_HOME}\"\n"
+	file := writeFile(t, filepath.Join(t.TempDir(), "config.toml"), content)
+
+	cfg := &encryptedConfig{}
+	loader := newFileLoader(file, config.WithLogger(&captureLogger{}),
+		config.WithInterpolation(true), config.WithStrictInterpolation(true))
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if cfg.Password != "pa${HOME}ss" || cfg.Home != "/home/app" {
+		t.Fatalf("password=%q home=%q", cfg.Password, cfg.Home)
+	}
+}
+
+type decryptedConfig struct {
+	config.Base
+	DSN      string   `toml:"dsn"`
+	Endpoint string   `toml:"endpoint"`
+	Hosts    []string `toml:"hosts"`
+}
+
+func TestDecryptedValuesRedacted(t *testing.T) {
+	key := newKey(t)
+	t.Setenv(config.EnvEncryptionKey, base64.StdEncoding.EncodeToString(key))
+	write := func(file, suffix string) {
+		writeFile(t, file, "dsn = \""+encrypt(t, key, "plain-dsn"+suffix)+"\"\n"+
+			"hosts = [\""+encrypt(t, key, "plain-host"+suffix)+"\"]\n"+
+			"[profiles.prod]\nendpoint = \""+encrypt(t, key, "plain-endpoint"+suffix)+"\"\n")
+	}
+	file := filepath.Join(t.TempDir(), "config.toml")
+	write(file, "")
+
+	logger := &captureLogger{}
+	cfg := &decryptedConfig{}
+	loader := newFileLoader(file, config.WithLogger(logger), config.WithProfile("prod"),
+		config.WithDumpMarshalledConfig(true), config.WithDumpFormat(config.DumpFormatJSON))
+	dump := captureOutput(t, &os.Stderr, func() {
+		if err := loader.Load(cfg); err != nil {
+			t.Fatalf("load failed, err=%v", err)
+		}
+	})
+	if cfg.DSN != "plain-dsn" || cfg.Endpoint != "plain-endpoint" || cfg.Hosts[0] != "plain-host" {
+		t.Fatalf("not decrypted, %+v", cfg)
+	}
+
+	rec := httptest.NewRecorder()
+	loader.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config?format=json", nil))
+	write(file, "-changed")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	changes := strings.Join(loader.ReloadStatus().LastChanges.Strings(), "\n")
+	if len(loader.ReloadStatus().LastChanges) != 3 {
+		t.Fatalf("changes %v", changes)
+	}
+
+	for name, out := range map[string]string{
+		"log":     strings.Join(logger.lines, "\n"),
+		"dump":    dump,
+		"admin":   rec.Body.String(),
+		"changes": changes,
+	} {
+		if strings.Contains(out, "plain-") {
+			t.Errorf("decrypted value in %v:\n%s", name, out)
+		}
+	}
+}
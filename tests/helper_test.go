@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+// flagResult replace the command line flags, which belong to go test
+type flagResult struct {
+	configFile string
+}
+
+func (f flagResult) ConfigFile() string { return f.configFile }
+func (f flagResult) DumpConfig() bool   { return false }
+func (f flagResult) ShowHelp() bool     { return false }
+func (f flagResult) ShowVersion() bool  { return false }
+func (f flagResult) Usage() func()      { return func() {} }
+
+// newFileLoader returns a loader reading the config file with the FileProvider
+func newFileLoader(path string, opts ...config.Option) *config.ConfigLoader {
+	opts = append([]config.Option{
+		config.WithProviders(&config.FileProvider{}),
+		config.WithFlagParser(func() config.FlagParseResult {
+			return flagResult{configFile: path}
+		}),
+	}, opts...)
+	return config.New(opts...)
+}
+
+// captureLogger record the log lines as "msg key=value ..."
+type captureLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *captureLogger) log(msg string, keysAndValues []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	line := msg
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		line += fmt.Sprintf(" %v=%v", keysAndValues[i], keysAndValues[i+1])
+	}
+	l.lines = append(l.lines, line)
+}
+
+func (l *captureLogger) Debugw(msg string, keysAndValues ...interface{}) { l.log(msg, keysAndValues) }
+func (l *captureLogger) Infow(msg string, keysAndValues ...interface{})  { l.log(msg, keysAndValues) }
+func (l *captureLogger) Warnw(msg string, keysAndValues ...interface{})  { l.log(msg, keysAndValues) }
+func (l *captureLogger) Errorw(msg string, keysAndValues ...interface{}) { l.log(msg, keysAndValues) }
+func (l *captureLogger) Fatalw(msg string, keysAndValues ...interface{}) { l.log(msg, keysAndValues) }
+
+// contains reports whether any log line contains s
+func (l *captureLogger) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.lines {
+		if strings.Contains(line, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureOutput replace *std like os.Stdout with a pipe while f runs and returns what was written to it
+func captureOutput(t *testing.T, std **os.File, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := *std
+	*std = w
+	defer func() { *std = orig }()
+
+	done := make(chan []byte)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = io.Copy(&buf, r)
+		done <- buf.Bytes()
+	}()
+	f()
+	_ = w.Close()
+	return string(<-done)
+}
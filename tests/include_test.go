@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+type includeConfig struct {
+	config.Base
+	Name   string `toml:"name"`
+	Common string `toml:"common"`
+	Port   int    `toml:"port"`
+}
+
+func loadInclude(file string, opts ...config.Option) (*includeConfig, error) {
+	cfg := &includeConfig{}
+	opts = append(opts, config.WithLogger(&captureLogger{}))
+	return cfg, newFileLoader(file, opts...).Load(cfg)
+}
+
+func TestIncludeGlobOrder(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "conf.d", "20-b.toml"), "name = \"b\"\ncommon = \"b\"\n[log]\nlevel = \"warn\"\n")
+	writeFile(t, filepath.Join(dir, "conf.d", "10-a.toml"), "name = \"a\"\ncommon = \"a\"\nport = 1\n")
+	writeFile(t, filepath.Join(dir, "conf.d", "ignored.yaml"), "name: ignored\n")
+	file := writeFile(t, filepath.Join(dir, "config.toml"), "include = [\"conf.d/*.toml\"]\nname = \"root\"\n")
+
+	cfg, err := loadInclude(file)
+	if err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	// the later included file wins over the earlier one, the including file wins over both
+	if cfg.Name != "root" || cfg.Common != "b" || cfg.Port != 1 || cfg.Log.Level != "warn" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+}
+
+func TestIncludeRelativePath(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "shared", "log.toml"), "[log]\nlevel = \"debug\"\n")
+	// relative to shared/common.toml, not to the root config file
+	writeFile(t, filepath.Join(dir, "shared", "common.toml"), "include = \"log.toml\"\ncommon = \"shared\"\n")
+	file := writeFile(t, filepath.Join(dir, "app", "config.toml"), "include = [\"../shared/common.toml\"]\nname = \"root\"\n")
+
+	cfg, err := loadInclude(file)
+	if err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if cfg.Name != "root" || cfg.Common != "shared" || cfg.Log.Level != "debug" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	writeFile(t, file, "include = [\"missing.toml\"]\n")
+	if _, err := loadInclude(file); err == nil || !strings.Contains(err.Error(), "missing.toml") {
+		t.Fatalf("want error of missing include, got %v", err)
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.toml"), "include = [\"b.toml\"]\n")
+	writeFile(t, filepath.Join(dir, "b.toml"), "include = [\"a.toml\"]\n")
+	file := writeFile(t, filepath.Join(dir, "config.toml"), "include = [\"a.toml\"]\nname = \"root\"\n")
+
+	if _, err := loadInclude(file); !errors.Is(err, config.ErrIncludeCycle) {
+		t.Fatalf("want include cycle error, got %v", err)
+	}
+}
+
+func TestIncludeDepth(t *testing.T) {
+	dir := t.TempDir()
+	chain := func(n int) string {
+		for i := 0; i < n; i++ {
+			writeFile(t, filepath.Join(dir, fmt.Sprintf("%d.toml", i)), fmt.Sprintf("include = [\"%d.toml\"]\n", i+1))
+		}
+		writeFile(t, filepath.Join(dir, fmt.Sprintf("%d.toml", n)), "name = \"deepest\"\n")
+		return filepath.Join(dir, "0.toml")
+	}
+
+	cfg, err := loadInclude(chain(config.MaxIncludeDepth))
+	if err != nil || cfg.Name != "deepest" {
+		t.Fatalf("include at max depth failed, name=%q err=%v", cfg.Name, err)
+	}
+	if _, err := loadInclude(chain(config.MaxIncludeDepth + 1)); err == nil || !strings.Contains(err.Error(), "include depth") {
+		t.Fatalf("want include depth error, got %v", err)
+	}
+}
+
+// the bare placeholder is valid toml only after interpolation, the includes must not be dropped
+func TestIncludeWithBarePlaceholder(t *testing.T) {
+	t.Setenv("TEST_INCLUDE_PORT", "8080")
+	t.Setenv("TEST_INCLUDE_LEVEL", "error")
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "common.toml"), "common = \"shared\"\n[log]\nlevel = \"${TEST_INCLUDE_LEVEL}\"\n")
+	file := writeFile(t, filepath.Join(dir, "config.toml"), "include = [\"common.toml\"]\nport = ${TEST_INCLUDE_PORT}\nname = \"$${TEST_INCLUDE_PORT}\"\n")
+
+	cfg, err := loadInclude(file, config.WithInterpolation(true))
+	if err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	// the escaped placeholder is not expanded again after the includes are merged
+	if cfg.Port != 8080 || cfg.Common != "shared" || cfg.Log.Level != "error" || cfg.Name != "${TEST_INCLUDE_PORT}" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/kk-kwok/config"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+func TestInterpolateSkipComments(t *testing.T) {
+	content := "# password = \"${UNDEFINED_VAR}\"\nname = \"a\" # ${UNDEFINED_VAR}\n"
+	got, err := config.Interpolate([]byte(content), true)
+	if err != nil {
+		t.Fatalf("interpolate failed, err=%v", err)
+	}
+	if string(got) != content {
+		t.Fatalf("comments changed, got %q", got)
+	}
+}
+
+func TestInterpolateEscapeToml(t *testing.T) {
+	value := "pa\"ss\\word\nline2"
+	t.Setenv("INTERPOLATE_VALUE", value)
+	content := "basic = \"${INTERPOLATE_VALUE}\"\n" +
+		"multi = \"\"\"\n${INTERPOLATE_VALUE}\"\"\"\n" +
+		"bare = ${INTERPOLATE_VALUE}\n" +
+		"url = 'http://host/#${INTERPOLATE_USER:-root}'\n"
+	got, err := config.Interpolate([]byte(content), true)
+	if err != nil {
+		t.Fatalf("interpolate failed, err=%v", err)
+	}
+	var doc map[string]string
+	if err := toml.Unmarshal(got, &doc); err != nil {
+		t.Fatalf("unmarshal %q failed, err=%v", got, err)
+	}
+	for _, key := range []string{"basic", "multi", "bare"} {
+		if doc[key] != value {
+			t.Errorf("%s = %q, want %q", key, doc[key], value)
+		}
+	}
+	if doc["url"] != "http://host/#root" {
+		t.Errorf("url = %q", doc["url"])
+	}
+}
+
+func TestInterpolateEscapeYaml(t *testing.T) {
+	value := "it's: \"quoted\" # not a comment"
+	t.Setenv("INTERPOLATE_VALUE", value)
+	content := "plain: ${INTERPOLATE_VALUE}\n" +
+		"double: \"${INTERPOLATE_VALUE}\"\n" +
+		"single: 'it''s ${INTERPOLATE_USER:-root}'\n" +
+		"note: it's ${INTERPOLATE_USER:-root}\n"
+	got, err := config.Interpolate([]byte(content), true)
+	if err != nil {
+		t.Fatalf("interpolate failed, err=%v", err)
+	}
+	var doc map[string]string
+	if err := yaml.Unmarshal(got, &doc); err != nil {
+		t.Fatalf("unmarshal %q failed, err=%v", got, err)
+	}
+	want := map[string]string{"plain": value, "double": value, "single": "it's root", "note": "it's root"}
+	for key, v := range want {
+		if doc[key] != v {
+			t.Errorf("%s = %q, want %q", key, doc[key], v)
+		}
+	}
+}
+
+func TestInterpolateSingleQuotedUnrepresentable(t *testing.T) {
+	t.Setenv("INTERPOLATE_VALUE", "a'b")
+	if _, err := config.Interpolate([]byte("key = '${INTERPOLATE_VALUE}'\n"), false); err == nil {
+		t.Fatal("want error for quote in single-quoted string")
+	}
+}
+
+func TestInterpolateEscaped(t *testing.T) {
+	got, err := config.Interpolate([]byte("key = \"$${HOME}\"\n"), true)
+	if err != nil {
+		t.Fatalf("interpolate failed, err=%v", err)
+	}
+	if string(got) != "key = \"${HOME}\"\n" {
+		t.Fatalf("got %q", got)
+	}
+}
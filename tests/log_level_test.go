@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/kk-kwok/config"
+)
+
+func waitLevel(t *testing.T, c *config.LevelController, want zapcore.Level) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Level() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("level = %v, want %v", c.Level(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLevelOverrideExpires(t *testing.T) {
+	c := config.NewLevelController(zap.NewAtomicLevelAt(zapcore.InfoLevel))
+	c.Override(zapcore.DebugLevel, 50*time.Millisecond)
+	if c.Level() != zapcore.DebugLevel {
+		t.Fatalf("level = %v after override", c.Level())
+	}
+	// the config level changed while overridden is applied on expiry
+	if err := c.SetConfigLevel("warn"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Level() != zapcore.DebugLevel {
+		t.Fatalf("config level applied while overridden, level = %v", c.Level())
+	}
+	waitLevel(t, c, zapcore.WarnLevel)
+}
+
+func TestLevelReOverride(t *testing.T) {
+	c := config.NewLevelController(zap.NewAtomicLevelAt(zapcore.InfoLevel))
+	for i := 0; i < 50; i++ {
+		c.Override(zapcore.DebugLevel, time.Millisecond)
+		time.Sleep(time.Millisecond)
+		c.Override(zapcore.ErrorLevel, time.Hour)
+		time.Sleep(2 * time.Millisecond)
+		if c.Level() != zapcore.ErrorLevel {
+			t.Fatalf("the timer of the previous override reverted the new one, level = %v", c.Level())
+		}
+	}
+}
+
+func serveLevel(t *testing.T, c *config.LevelController, method, contentType, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(method, "/log/level", strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, req)
+	resp := map[string]interface{}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %q failed, err=%v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func TestLevelHandler(t *testing.T) {
+	c := config.NewLevelController(zap.NewAtomicLevelAt(zapcore.InfoLevel))
+
+	code, resp := serveLevel(t, c, http.MethodGet, "", "")
+	if code != http.StatusOK || resp["level"] != "info" || resp["expires_at"] != nil {
+		t.Fatalf("GET = %v %v", code, resp)
+	}
+
+	code, resp = serveLevel(t, c, http.MethodPut, "application/json", `{"level":"debug","ttl":"1h"}`)
+	if code != http.StatusOK || resp["level"] != "debug" || resp["expires_at"] == nil {
+		t.Fatalf("PUT json = %v %v", code, resp)
+	}
+	if c.Level() != zapcore.DebugLevel {
+		t.Fatalf("level = %v after PUT", c.Level())
+	}
+
+	code, resp = serveLevel(t, c, http.MethodPut, "application/x-www-form-urlencoded", "level=error&ttl=1h")
+	if code != http.StatusOK || resp["level"] != "error" {
+		t.Fatalf("PUT form = %v %v", code, resp)
+	}
+
+	code, resp = serveLevel(t, c, http.MethodDelete, "", "")
+	if code != http.StatusOK || resp["level"] != "info" || resp["expires_at"] != nil {
+		t.Fatalf("DELETE = %v %v", code, resp)
+	}
+
+	for _, body := range []string{`{"level":"loud"}`, `{"ttl":"1m"}`, `{"level":"debug","ttl":"soon"}`, `not json`} {
+		if code, resp = serveLevel(t, c, http.MethodPut, "application/json", body); code != http.StatusBadRequest || resp["error"] == nil {
+			t.Fatalf("PUT %s = %v %v", body, code, resp)
+		}
+	}
+	if code, _ = serveLevel(t, c, http.MethodPost, "", ""); code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST = %v", code)
+	}
+	if c.Level() != zapcore.InfoLevel {
+		t.Fatalf("level = %v after bad requests", c.Level())
+	}
+}
+
+type levelConfig struct {
+	config.Base
+}
+
+func TestLevelFollowsConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "[log]\nlevel = \"warn\"\n")
+
+	c := config.NewLevelController(zap.NewAtomicLevelAt(zapcore.InfoLevel))
+	log := &captureLogger{}
+	loader := newFileLoader(file, config.WithLevelController(c), config.WithLogger(log))
+	if err := loader.Load(&levelConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if c.Level() != zapcore.WarnLevel {
+		t.Fatalf("level = %v after load", c.Level())
+	}
+	// binding again must not subscribe twice
+	if err := c.Bind(loader); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, file, "[log]\nlevel = \"error\"\n")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	if c.Level() != zapcore.ErrorLevel {
+		t.Fatalf("level = %v after reload", c.Level())
+	}
+	changed := 0
+	for _, line := range log.lines {
+		if strings.HasPrefix(line, "log level changed by config") {
+			changed++
+		}
+	}
+	if changed != 1 {
+		t.Fatalf("level change applied %d times, log=%v", changed, log.lines)
+	}
+}
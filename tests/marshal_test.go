@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	tomlv2 "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kk-kwok/config"
+)
+
+type marshalConfig struct {
+	config.Base
+	ServerPort int      `toml:"server_port" json:"port"`
+	Upstream   upstream `toml:"upstream"`
+}
+
+type upstream struct {
+	Hosts []string `toml:"hosts"`
+	Token string   `toml:"api_token"`
+}
+
+func newMarshalConfig() *marshalConfig {
+	cfg := &marshalConfig{ServerPort: 8080, Upstream: upstream{Hosts: []string{"a", "b"}, Token: "t 1"}}
+	cfg.Log.Level = "debug"
+	return cfg
+}
+
+// the key names follow the toml tags in every format, so a dump can be read back as config
+func TestMarshalIndentFormats(t *testing.T) {
+	decoders := map[config.DumpFormat]func([]byte, interface{}) error{
+		config.DumpFormatTOML: tomlv2.Unmarshal,
+		config.DumpFormatYAML: yaml.Unmarshal,
+		config.DumpFormatJSON: json.Unmarshal,
+	}
+	for format, decode := range decoders {
+		text, err := config.MarshalIndent(newMarshalConfig(), format)
+		if err != nil {
+			t.Fatalf("%v marshal failed, err=%v", format, err)
+		}
+		tree := map[string]interface{}{}
+		if err := decode([]byte(text), &tree); err != nil {
+			t.Fatalf("%v decode failed, err=%v text=%s", format, err, text)
+		}
+		if _, ok := tree["server_port"]; !ok {
+			t.Fatalf("%v has no server_port key, text=%s", format, text)
+		}
+		for _, key := range []string{"port", "ServerPort", "Base", "Upstream"} {
+			if _, ok := tree[key]; ok {
+				t.Fatalf("%v has key %v, text=%s", format, key, text)
+			}
+		}
+		up, _ := tree["upstream"].(map[string]interface{})
+		if up["api_token"] != "t 1" {
+			t.Fatalf("%v upstream = %v, text=%s", format, tree["upstream"], text)
+		}
+		log, _ := tree["log"].(map[string]interface{})
+		if log["level"] != "debug" {
+			t.Fatalf("%v log = %v, text=%s", format, tree["log"], text)
+		}
+	}
+
+	text, err := config.MarshalIndent(newMarshalConfig(), config.DumpFormatEnv)
+	if err != nil {
+		t.Fatalf("env marshal failed, err=%v", err)
+	}
+	for _, line := range []string{"SERVER_PORT=8080", "LOG__LEVEL=debug", `UPSTREAM__HOSTS="[\"a\",\"b\"]"`, `UPSTREAM__API_TOKEN="t 1"`} {
+		if !strings.Contains(text, line+"\n") {
+			t.Fatalf("env dump has no line %v, text=%s", line, text)
+		}
+	}
+
+	if _, err := config.MarshalIndent(newMarshalConfig(), "xml"); err == nil {
+		t.Fatal("unknown format not rejected")
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/kk-kwok/config"
+)
+
+// metricValue is the value of a gauge, a counter or the sample count of a histogram
+type metricValue struct {
+	value   float64
+	samples uint64
+}
+
+// gather returns the metrics of the family by their labels joined like provider=file,result=success
+func gather(t *testing.T, registry *prometheus.Registry, name string) map[string]metricValue {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("gather failed, err=%v", err)
+	}
+	metrics := map[string]metricValue{}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			key := ""
+			for _, label := range m.GetLabel() {
+				if key != "" {
+					key += ","
+				}
+				key += label.GetName() + "=" + label.GetValue()
+			}
+			metrics[key] = metricValue{
+				value:   m.GetGauge().GetValue() + m.GetCounter().GetValue(),
+				samples: m.GetHistogram().GetSampleCount(),
+			}
+		}
+	}
+	return metrics
+}
+
+func TestLoaderMetrics(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "name = \"v1\"\n")
+	registry := prometheus.NewRegistry()
+	loader := newFileLoader(file, config.WithLogger(&captureLogger{}), config.WithMetricsRegisterer(registry))
+	if err := loader.Load(&nameConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+
+	fetches := gather(t, registry, "config_provider_fetch_duration_seconds")
+	if m := fetches["provider=file,result=success"]; m.samples != 1 {
+		t.Fatalf("fetch duration after load = %v", fetches)
+	}
+	if m := gather(t, registry, "config_last_success_timestamp_seconds")[""]; m.value <= 0 {
+		t.Fatalf("last success after load = %v", m)
+	}
+	info := gather(t, registry, "config_info")
+	if len(info) != 1 {
+		t.Fatalf("config_info after load = %v", info)
+	}
+	var loadedInfo string
+	for labels, m := range info {
+		loadedInfo = labels
+		if m.value != 1 {
+			t.Fatalf("config_info %v = %v", labels, m.value)
+		}
+	}
+	if len(gather(t, registry, "config_reloads_total")) != 0 {
+		t.Fatal("reloads counted after load")
+	}
+
+	// a failed reload is counted and keeps the info of the current config
+	writeFile(t, file, "name = \n")
+	if err := loader.Reload(); err == nil {
+		t.Fatal("reload of invalid config succeeded")
+	}
+	reloads := gather(t, registry, "config_reloads_total")
+	if m := reloads["result=failure"]; m.value != 1 || len(reloads) != 1 {
+		t.Fatalf("reloads after failed reload = %v", reloads)
+	}
+	if _, ok := gather(t, registry, "config_info")[loadedInfo]; !ok {
+		t.Fatalf("config_info changed by the failed reload, got %v", gather(t, registry, "config_info"))
+	}
+
+	writeFile(t, file, "name = \"v2\"\n")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	reloads = gather(t, registry, "config_reloads_total")
+	if m := reloads["result=success"]; m.value != 1 {
+		t.Fatalf("reloads after reload = %v", reloads)
+	}
+	info = gather(t, registry, "config_info")
+	if _, ok := info[loadedInfo]; ok || len(info) != 1 {
+		t.Fatalf("config_info after reload = %v", info)
+	}
+	fetches = gather(t, registry, "config_provider_fetch_duration_seconds")
+	if m := fetches["provider=file,result=success"]; m.samples != 3 {
+		t.Fatalf("fetch duration after reloads = %v", fetches)
+	}
+}
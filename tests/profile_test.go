@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+func writeProfileConfig(t *testing.T) string {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.toml")
+	writeFile(t, file, `name = "base"
+[log]
+level = "info"
+
+[profiles.prod]
+name = "section"
+[profiles.prod.log]
+level = "warn"
+`)
+	writeFile(t, filepath.Join(dir, "config.prod.toml"), "name = \"overlay\"\n")
+	return file
+}
+
+func TestProfileSectionAndOverlay(t *testing.T) {
+	log := &captureLogger{}
+	cfg := &nameConfig{}
+	loader := newFileLoader(writeProfileConfig(t), config.WithProfile("prod"), config.WithLogger(log))
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	// the overlay file wins over the section, which wins over the base
+	if cfg.Name != "overlay" || cfg.Log.Level != "warn" || cfg.ActiveProfile() != "prod" || loader.Profile() != "prod" {
+		t.Fatalf("name = %q level = %q profile = %q", cfg.Name, cfg.Log.Level, cfg.ActiveProfile())
+	}
+
+	applied := 0
+	for _, line := range log.lines {
+		if strings.HasPrefix(line, "config profile section applied") {
+			applied++
+		}
+	}
+	if applied != 1 {
+		t.Fatalf("profile section applied %d times, want once", applied)
+	}
+}
+
+func TestProfileEnvOptIn(t *testing.T) {
+	t.Setenv(config.EnvAppEnv, "prod")
+	file := writeProfileConfig(t)
+
+	cfg := &nameConfig{}
+	if err := newFileLoader(file, config.WithLogger(&captureLogger{})).Load(cfg); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if cfg.Name != "base" || cfg.ActiveProfile() != "" {
+		t.Fatalf("APP_ENV must be ignored by default, name = %q profile = %q", cfg.Name, cfg.ActiveProfile())
+	}
+
+	cfg = &nameConfig{}
+	if err := newFileLoader(file, config.WithLogger(&captureLogger{}), config.WithProfileEnv(config.EnvAppEnv)).Load(cfg); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if cfg.Name != "overlay" || cfg.ActiveProfile() != "prod" {
+		t.Fatalf("name = %q profile = %q", cfg.Name, cfg.ActiveProfile())
+	}
+}
+
+// customConfig implements BaseConfigEmbedded without embedding Base
+type customConfig struct {
+	Name string `toml:"name"`
+}
+
+func (c *customConfig) InitOtlpGrpcEndpointFromEnv() {}
+
+func TestCustomBaseConfigEmbedded(t *testing.T) {
+	cfg := &customConfig{}
+	if err := newFileLoader(writeProfileConfig(t), config.WithProfile("prod"), config.WithLogger(&captureLogger{})).Load(cfg); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if cfg.Name != "overlay" {
+		t.Fatalf("name = %q", cfg.Name)
+	}
+}
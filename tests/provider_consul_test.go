@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kk-kwok/config"
+)
+
+type consulKV struct {
+	Key   string
+	Value []byte
+}
+
+// consulStandIn serve /v1/kv like consul, the responses are scripted per request
+type consulStandIn struct {
+	t        *testing.T
+	mu       sync.Mutex
+	queries  []string // the index query of each request, empty for non-blocking
+	waits    []string
+	respond  func(n int, w http.ResponseWriter, r *http.Request)
+	requests chan int
+}
+
+func newConsulStandIn(t *testing.T, respond func(n int, w http.ResponseWriter, r *http.Request)) (*consulStandIn, *httptest.Server) {
+	s := &consulStandIn{t: t, respond: respond, requests: make(chan int, 100)}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func (s *consulStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.queries = append(s.queries, r.URL.Query().Get("index"))
+	s.waits = append(s.waits, r.URL.Query().Get("wait"))
+	n := len(s.queries)
+	s.mu.Unlock()
+	s.requests <- n
+	s.respond(n, w, r)
+}
+
+func (s *consulStandIn) query(n int) (index, wait string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries[n-1], s.waits[n-1]
+}
+
+// waitRequest wait until the nth request arrived
+func (s *consulStandIn) waitRequest(n int) {
+	s.t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case got := <-s.requests:
+			if got >= n {
+				return
+			}
+		case <-timeout:
+			s.t.Fatalf("request %d not received", n)
+		}
+	}
+}
+
+func writeKV(w http.ResponseWriter, index uint64, pairs ...consulKV) {
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+	_ = json.NewEncoder(w).Encode(pairs)
+}
+
+// block until the watch is canceled
+func block(w http.ResponseWriter, r *http.Request) {
+	<-r.Context().Done()
+}
+
+func TestConsulProviderBlockingQuery(t *testing.T) {
+	changed := make(chan string, 10)
+	s, srv := newConsulStandIn(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 1:
+			writeKV(w, 10, consulKV{Key: "app/config", Value: []byte("name = \"v1\"\n")})
+		case 2:
+			writeKV(w, 11, consulKV{Key: "app/config", Value: []byte("name = \"v2\"\n")})
+		default:
+			block(w, r)
+		}
+	})
+	provider := &config.ConsulProvider{
+		Addr:     srv.URL,
+		Key:      "app/config",
+		WaitTime: time.Second,
+		ChangeListener: func(namespace, group, dataID, data string) {
+			changed <- data
+		},
+	}
+	loader, cfg := loadProvider(t, provider)
+	defer loader.Close()
+	if cfg.Name != "v1" {
+		t.Fatalf("name = %q", cfg.Name)
+	}
+
+	select {
+	case data := <-changed:
+		if data != "name = \"v2\"\n" {
+			t.Fatalf("changed data = %q", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not notified")
+	}
+	s.waitRequest(3)
+	for n, want := range []string{"", "10", "11"} {
+		if index, wait := s.query(n + 1); index != want || (want != "" && wait != "1s") {
+			t.Errorf("request %d index=%q wait=%q, want index=%q wait=1s", n+1, index, wait, want)
+		}
+	}
+}
+
+func TestConsulProviderIndexReset(t *testing.T) {
+	changed := make(chan string, 10)
+	s, srv := newConsulStandIn(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 1:
+			writeKV(w, 10, consulKV{Key: "app/config", Value: []byte("name = \"v1\"\n")})
+		case 2:
+			// the index went backwards, e.g. after a snapshot restore
+			writeKV(w, 5, consulKV{Key: "app/config", Value: []byte("name = \"v2\"\n")})
+		case 3:
+			writeKV(w, 6, consulKV{Key: "app/config", Value: []byte("name = \"v2\"\n")})
+		default:
+			block(w, r)
+		}
+	})
+	provider := &config.ConsulProvider{
+		Addr: srv.URL,
+		Key:  "app/config",
+		ChangeListener: func(namespace, group, dataID, data string) {
+			changed <- data
+		},
+	}
+	loader, _ := loadProvider(t, provider)
+	defer loader.Close()
+
+	s.waitRequest(4)
+	for n, want := range []string{"", "10", "1", "6"} {
+		if index, _ := s.query(n + 1); index != want {
+			t.Errorf("request %d index=%q, want %q", n+1, index, want)
+		}
+	}
+	// notified once for v2, the read after the reset returns the same content
+	if len(changed) != 1 {
+		t.Fatalf("changes = %d, want 1", len(changed))
+	}
+}
+
+func TestConsulProviderMissingIndex(t *testing.T) {
+	s, srv := newConsulStandIn(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		// no X-Consul-Index, each query returns immediately
+		_ = json.NewEncoder(w).Encode([]consulKV{{Key: "app/config", Value: []byte("name = \"v1\"\n")}})
+	})
+	provider := &config.ConsulProvider{
+		Addr:           srv.URL,
+		Key:            "app/config",
+		ChangeListener: func(namespace, group, dataID, data string) {},
+	}
+	loader, _ := loadProvider(t, provider)
+	defer loader.Close()
+
+	s.waitRequest(3)
+	time.Sleep(500 * time.Millisecond)
+	s.mu.Lock()
+	n := len(s.queries)
+	s.mu.Unlock()
+	// the first read, then about one query per second
+	if n > 5 {
+		t.Fatalf("the watch spins on a missing index, %d requests", n)
+	}
+	for i := 2; i <= 3; i++ {
+		if index, _ := s.query(i); index != "1" {
+			t.Errorf("request %d index=%q, want 1", i, index)
+		}
+	}
+}
+
+func TestConsulProviderPrefixMerge(t *testing.T) {
+	_, srv := newConsulStandIn(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("recurse") != "true" {
+			http.Error(w, "recurse expected", http.StatusBadRequest)
+			return
+		}
+		// unsorted, the later key in lexical order wins
+		writeKV(w, 3,
+			consulKV{Key: "app/20-override", Value: []byte("name = \"override\"\n")},
+			consulKV{Key: "app/"},
+			consulKV{Key: "app/10-base", Value: []byte("name = \"base\"\n[log]\nlevel = \"debug\"\n")},
+		)
+	})
+	_, cfg := loadProvider(t, &config.ConsulProvider{Addr: srv.URL, Key: "app/", Prefix: true})
+	if cfg.Name != "override" || cfg.Log.Level != "debug" {
+		t.Fatalf("name = %q level = %q", cfg.Name, cfg.Log.Level)
+	}
+}
+
+func TestConsulProviderNotFound(t *testing.T) {
+	_, srv := newConsulStandIn(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Consul-Index", "1")
+		w.WriteHeader(http.StatusNotFound)
+	})
+	logger := &captureLogger{}
+	loader := config.New(
+		config.WithProviders(&config.ConsulProvider{Addr: srv.URL, Key: "app/missing"}),
+		config.WithFlagParser(func() config.FlagParseResult { return flagResult{} }),
+		config.WithLogger(logger),
+	)
+	if err := loader.Load(&nameConfig{}); err == nil {
+		t.Fatal("want error for missing key")
+	}
+	if !logger.contains("key not found") {
+		t.Fatalf("missing key not reported, logs=%v", logger.lines)
+	}
+}
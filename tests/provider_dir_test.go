@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kk-kwok/config"
+)
+
+type dirConfig struct {
+	config.Base
+	Name  string `toml:"name"`
+	Port  int    `toml:"port"`
+	Debug bool   `toml:"debug"`
+}
+
+func loadDir(configFile string, providers ...config.Provider) (*dirConfig, *captureLogger, error) {
+	cfg := &dirConfig{}
+	log := &captureLogger{}
+	loader := config.New(
+		config.WithProviders(providers...),
+		config.WithFlagParser(func() config.FlagParseResult { return flagResult{configFile: configFile} }),
+		config.WithLogger(log),
+	)
+	return cfg, log, loader.Load(cfg)
+}
+
+func TestDirProviderOrder(t *testing.T) {
+	dir := t.TempDir()
+	// written out of order, merged in lexical order so the later fragment wins
+	writeFile(t, filepath.Join(dir, "99-local.toml"), "port = 9999\n")
+	writeFile(t, filepath.Join(dir, "10-base.toml"), "name = \"base\"\nport = 80\ndebug = true\n")
+	writeFile(t, filepath.Join(dir, "50-app.toml"), "name = \"app\"\nport = 8080\n")
+	writeFile(t, filepath.Join(dir, "60-blank.toml"), "\n  \n")
+	writeFile(t, filepath.Join(dir, "70-other.yaml"), "port: 1\n")
+
+	cfg, _, err := loadDir(dir, &config.DirProvider{})
+	if err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if cfg.Name != "app" || cfg.Port != 9999 || !cfg.Debug {
+		t.Fatalf("config = %+v", cfg)
+	}
+
+	// only the fragments matching the pattern are read
+	cfg, _, err = loadDir(dir, &config.DirProvider{Pattern: "[15]*.toml"})
+	if err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if cfg.Name != "app" || cfg.Port != 8080 {
+		t.Fatalf("config with pattern = %+v", cfg)
+	}
+}
+
+func TestDirProviderSkip(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "10-base.toml"), "name = \"dir\"\n")
+	file := writeFile(t, filepath.Join(t.TempDir(), "config.toml"), "name = \"file\"\n")
+	fallback := &config.TextProvider{ConfigText: []byte("name = \"text\"\n")}
+
+	cases := []struct {
+		name       string
+		configFile string
+		provider   *config.DirProvider
+		want       string
+	}{
+		{"flag dir", dir, &config.DirProvider{DefaultConfigDir: filepath.Join(dir, "missing")}, "dir"},
+		{"default dir", "", &config.DirProvider{DefaultConfigDir: dir}, "dir"},
+		{"flag file left to the next provider", file, &config.DirProvider{DefaultConfigDir: dir}, "text"},
+		{"empty path", "", &config.DirProvider{SkipIfPathEmpty: true}, "text"},
+		{"default not exists", "", &config.DirProvider{DefaultConfigDir: filepath.Join(dir, "missing"), SkipIfDefaultNotExist: true}, "text"},
+	}
+	for _, c := range cases {
+		cfg, log, err := loadDir(c.configFile, c.provider, fallback)
+		if err != nil {
+			t.Fatalf("%v: load failed, err=%v", c.name, err)
+		}
+		if cfg.Name != c.want {
+			t.Fatalf("%v: name = %q, want %q", c.name, cfg.Name, c.want)
+		}
+		if skipped := log.contains("config provider skipped provider=dir"); skipped != (c.want == "text") {
+			t.Fatalf("%v: skipped = %v, log=%v", c.name, skipped, log.lines)
+		}
+	}
+
+	// an error instead of a skip without the flags
+	if _, _, err := loadDir("", &config.DirProvider{}); !errors.Is(err, config.ErrEmptyConfigDir) {
+		t.Fatalf("empty path err = %v, want ErrEmptyConfigDir", err)
+	}
+	if _, _, err := loadDir("", &config.DirProvider{DefaultConfigDir: filepath.Join(dir, "missing")}); err == nil {
+		t.Fatal("missing default dir not reported")
+	}
+	// SkipIfDefaultNotExist does not apply to the dir from the flag
+	if _, _, err := loadDir(filepath.Join(dir, "missing"), &config.DirProvider{SkipIfDefaultNotExist: true}); errors.Is(err, config.ErrSkipProvider) || err == nil {
+		t.Fatalf("missing flag dir err = %v", err)
+	}
+}
+
+func TestDirProviderEmpty(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := loadDir(dir, &config.DirProvider{}); !errors.Is(err, config.ErrEmptyConfig) {
+		t.Fatalf("empty dir err = %v, want ErrEmptyConfig", err)
+	}
+	writeFile(t, filepath.Join(dir, "10-blank.toml"), "\n")
+	if _, _, err := loadDir(dir, &config.DirProvider{}); !errors.Is(err, config.ErrEmptyConfig) {
+		t.Fatalf("dir of blank fragments err = %v, want ErrEmptyConfig", err)
+	}
+}
+
+func TestDirProviderWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "10-base.toml"), "name = \"v1\"\nport = 80\n")
+
+	changed := make(chan string, 10)
+	provider := &config.DirProvider{
+		WatchInterval: 20 * time.Millisecond,
+		ChangeListener: func(namespace, group, dataID, data string) {
+			changed <- dataID
+		},
+	}
+	cfg := &dirConfig{}
+	loader := config.New(
+		config.WithProviders(provider),
+		config.WithFlagParser(func() config.FlagParseResult { return flagResult{configFile: dir} }),
+		config.WithLogger(&captureLogger{}),
+		config.WithReloadOnChange(true),
+	)
+	reloaded := make(chan *dirConfig, 10)
+	loader.OnReload(func(event config.ReloadEvent) { reloaded <- event.New.(*dirConfig) })
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	defer loader.Close()
+
+	wait := func(check func(*dirConfig) bool) {
+		t.Helper()
+		for {
+			select {
+			case cfg := <-reloaded:
+				if check(cfg) {
+					return
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("config not reloaded, current=%+v", loader.Current())
+			}
+		}
+	}
+	// a changed fragment
+	writeFile(t, filepath.Join(dir, "10-base.toml"), "name = \"v2\"\nport = 80\n")
+	wait(func(cfg *dirConfig) bool { return cfg.Name == "v2" })
+	// a new fragment is picked up
+	writeFile(t, filepath.Join(dir, "20-port.toml"), "port = 8080\n")
+	wait(func(cfg *dirConfig) bool { return cfg.Port == 8080 })
+	if got := <-changed; got != dir {
+		t.Fatalf("ChangeListener dataID = %q, want %q", got, dir)
+	}
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+type envServer struct {
+	Addr  string   `toml:"addr"`
+	Port  int      `toml:"port"`
+	Tags  []string `toml:"tags"`
+	Ports []int    `toml:"ports"`
+}
+
+type envConfig struct {
+	config.Base
+	Name   string    `toml:"name"`
+	Server envServer `toml:"server"`
+}
+
+func TestEnvMarshalRoundTrip(t *testing.T) {
+	want := &envConfig{
+		Name:   "demo app #1",
+		Server: envServer{Addr: "0.0.0.0", Port: 8080, Tags: []string{"a", "b c"}, Ports: []int{80, 443}},
+	}
+	want.Log.Level = "debug"
+	want.MetricGo = true
+
+	text, err := config.EnvMarshal(want)
+	if err != nil {
+		t.Fatalf("marshal failed, err=%v", err)
+	}
+	if !strings.Contains(text, "SERVER__ADDR=0.0.0.0\n") || !strings.Contains(text, "METRIC_GO=true\n") {
+		t.Fatalf("unexpected env output:\n%s", text)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		name, value, _ := strings.Cut(line, "=")
+		t.Setenv("APP_"+name, value)
+	}
+
+	got := &envConfig{}
+	loader := config.New(
+		config.WithProviders(&config.EnvProvider{Prefix: "APP_"}),
+		config.WithFlagParser(func() config.FlagParseResult { return flagResult{} }),
+		config.WithLogger(&captureLogger{}),
+	)
+	if err := loader.Load(got); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if got.Name != want.Name || !reflect.DeepEqual(got.Server, want.Server) || got.Log.Level != "debug" || !got.MetricGo {
+		t.Fatalf("round trip mismatch\nwant %+v\ngot  %+v", want, got)
+	}
+}
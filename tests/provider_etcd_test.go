@@ -0,0 +1,298 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kk-kwok/config"
+)
+
+type etcdKV struct {
+	Key         []byte `json:"key"`
+	Value       []byte `json:"value,omitempty"`
+	ModRevision string `json:"mod_revision,omitempty"`
+}
+
+type etcdHistory struct {
+	revision int64
+	kv       etcdKV
+}
+
+// etcdStandIn serve the etcd v3 json grpc gateway endpoints used by EtcdProvider from memory. an embedded etcd
+// server (go.etcd.io/etcd/server/v3/embed) is not used since it requires newer grpc and otel than this module pins
+type etcdStandIn struct {
+	mu       sync.Mutex
+	kvs      map[string]string
+	revision int64
+	history  []etcdHistory
+	watchers []chan etcdHistory
+
+	// silent makes the watch streams send nothing, like a silently dropped connection
+	silent bool
+	// watchStarts the start_revision of each watch request
+	watchStarts []int64
+
+	username  string
+	tokens    int  // tokens issued
+	expireOne bool // reject the first token once with 401
+}
+
+func newEtcdStandIn(t *testing.T) (*etcdStandIn, *httptest.Server) {
+	s := &etcdStandIn{kvs: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/kv/range", s.serveRange)
+	mux.HandleFunc("/v3/watch", s.serveWatch)
+	mux.HandleFunc("/v3/auth/authenticate", s.serveAuth)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func (s *etcdStandIn) put(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revision++
+	s.kvs[key] = value
+	h := etcdHistory{revision: s.revision, kv: etcdKV{Key: []byte(key), Value: []byte(value)}}
+	s.history = append(s.history, h)
+	if s.silent {
+		return
+	}
+	for _, w := range s.watchers {
+		w <- h
+	}
+}
+
+func (s *etcdStandIn) setSilent(silent bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.silent = silent
+}
+
+func (s *etcdStandIn) starts() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int64{}, s.watchStarts...)
+}
+
+// authorized check the token, false if the response is written
+func (s *etcdStandIn) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if s.username == "" {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token := r.Header.Get("Authorization")
+	if token == "" || token == "token-1" && s.expireOne {
+		s.expireOne = false
+		http.Error(w, `{"error":"invalid auth token"}`, http.StatusUnauthorized)
+		return false
+	}
+	return token == fmt.Sprintf("token-%d", s.tokens)
+}
+
+func (s *etcdStandIn) serveAuth(w http.ResponseWriter, r *http.Request) {
+	var req struct{ Name, Password string }
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Name != s.username || req.Password != "pass" {
+		http.Error(w, `{"error":"authentication failed"}`, http.StatusBadRequest)
+		return
+	}
+	s.tokens++
+	_ = json.NewEncoder(w).Encode(map[string]string{"token": fmt.Sprintf("token-%d", s.tokens)})
+}
+
+type etcdRangeRequest struct {
+	Key           []byte `json:"key"`
+	RangeEnd      []byte `json:"range_end"`
+	StartRevision string `json:"start_revision"`
+}
+
+func (r etcdRangeRequest) match(key string) bool {
+	if r.RangeEnd == nil {
+		return key == string(r.Key)
+	}
+	return key >= string(r.Key) && (string(r.RangeEnd) == "\x00" || key < string(r.RangeEnd))
+}
+
+func (s *etcdStandIn) serveRange(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+	var req etcdRangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var kvs []etcdKV
+	for k, v := range s.kvs {
+		if req.match(k) {
+			kvs = append(kvs, etcdKV{Key: []byte(k), Value: []byte(v)})
+		}
+	}
+	// etcd returns the keys sorted, reverse them to check the provider sorts
+	sort.Slice(kvs, func(i, j int) bool { return string(kvs[i].Key) > string(kvs[j].Key) })
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"header": map[string]string{"revision": strconv.FormatInt(s.revision, 10)},
+		"kvs":    kvs,
+	})
+}
+
+func (s *etcdStandIn) serveWatch(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+	var req struct {
+		CreateRequest etcdRangeRequest `json:"create_request"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	start, _ := strconv.ParseInt(req.CreateRequest.StartRevision, 10, 64)
+	events := make(chan etcdHistory, 100)
+
+	s.mu.Lock()
+	s.watchStarts = append(s.watchStarts, start)
+	silent := s.silent
+	var replay []etcdHistory
+	for _, h := range s.history {
+		if h.revision >= start {
+			replay = append(replay, h)
+		}
+	}
+	s.watchers = append(s.watchers, events)
+	s.mu.Unlock()
+
+	flusher := w.(http.Flusher)
+	send := func(h etcdHistory, created bool) {
+		result := map[string]interface{}{
+			"header": map[string]string{"revision": strconv.FormatInt(h.revision, 10)},
+		}
+		if created {
+			result["created"] = true
+		} else {
+			result["events"] = []map[string]interface{}{{"kv": h.kv}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
+		flusher.Flush()
+	}
+	if !silent {
+		send(etcdHistory{revision: start - 1}, true)
+		for _, h := range replay {
+			if req.CreateRequest.match(string(h.kv.Key)) {
+				send(h, false)
+			}
+		}
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case h := <-events:
+			if req.CreateRequest.match(string(h.kv.Key)) {
+				send(h, false)
+			}
+		}
+	}
+}
+
+func TestEtcdProviderPrefixMerge(t *testing.T) {
+	s, srv := newEtcdStandIn(t)
+	s.put("app/10-base", "name = \"base\"\n[log]\nlevel = \"debug\"\n")
+	s.put("app/20-override", "name = \"override\"\n")
+	s.put("app0/outside", "name = \"outside\"\n")
+
+	_, cfg := loadProvider(t, &config.EtcdProvider{Endpoints: []string{srv.URL}, Key: "app/", Prefix: true})
+	if cfg.Name != "override" || cfg.Log.Level != "debug" {
+		t.Fatalf("name = %q level = %q", cfg.Name, cfg.Log.Level)
+	}
+}
+
+func TestEtcdProviderWatch(t *testing.T) {
+	s, srv := newEtcdStandIn(t)
+	s.put("app/config", "name = \"v1\"\n")
+
+	changed := make(chan string, 10)
+	provider := &config.EtcdProvider{
+		Endpoints:        []string{srv.URL},
+		Key:              "app/config",
+		WatchIdleTimeout: 100 * time.Millisecond,
+		ChangeListener: func(namespace, group, dataID, data string) {
+			changed <- data
+		},
+	}
+	loader, _ := loadProvider(t, provider)
+	defer loader.Close()
+
+	waitChange := func(want string) {
+		t.Helper()
+		select {
+		case data := <-changed:
+			if data != want {
+				t.Fatalf("changed data = %q, want %q", data, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("change to %q not notified", want)
+		}
+	}
+	waitWatches := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for len(s.starts()) < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("watch requests = %d, want %d", len(s.starts()), n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitWatches(1)
+	s.put("app/config", "name = \"v2\"\n")
+	waitChange("name = \"v2\"\n")
+
+	// the update during a silently dropped connection is replayed from the last seen revision after the idle reconnect
+	s.setSilent(true)
+	waitWatches(len(s.starts()) + 1)
+	s.put("app/config", "name = \"v3\"\n")
+	s.setSilent(false)
+	waitChange("name = \"v3\"\n")
+
+	starts := s.starts()
+	if starts[0] != 2 {
+		t.Errorf("first watch start_revision = %d, want 2", starts[0])
+	}
+	for _, start := range starts[1:] {
+		if start < 3 || start > 4 {
+			t.Errorf("reconnect start_revision = %d, want 3 or 4", start)
+		}
+	}
+}
+
+func TestEtcdProviderAuth(t *testing.T) {
+	s, srv := newEtcdStandIn(t)
+	s.username = "app"
+	s.expireOne = true
+	s.put("app/config", "name = \"authed\"\n")
+
+	_, cfg := loadProvider(t, &config.EtcdProvider{Endpoints: []string{srv.URL}, Key: "app/config", Username: "app", Password: "pass"})
+	if cfg.Name != "authed" {
+		t.Fatalf("name = %q", cfg.Name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokens != 2 {
+		t.Fatalf("tokens issued = %d, want 2 after the expired token", s.tokens)
+	}
+}
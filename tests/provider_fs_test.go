@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kk-kwok/config"
+)
+
+var defaultConfigs = fstest.MapFS{
+	"configs/default.toml": {Data: []byte("name = \"default\"\n[log]\nlevel = \"info\"\n")},
+	"configs/dev.toml":     {Data: []byte("name = \"dev\"\n[log]\nlevel = \"debug\"\n")},
+	"configs/empty.toml":   {Data: []byte{}},
+	"envs/dev/app.toml":    {Data: []byte("name = \"templated\"\n")},
+}
+
+func TestFSProviderSelectEnv(t *testing.T) {
+	t.Setenv(config.EnvAppEnv, "")
+	for _, tc := range []struct {
+		name     string
+		provider *config.FSProvider
+		opts     []config.Option
+		want     string
+	}{
+		{"default", &config.FSProvider{FS: defaultConfigs}, nil, "default"},
+		{"env", &config.FSProvider{FS: defaultConfigs, Env: "dev"}, nil, "dev"},
+		{"profile", &config.FSProvider{FS: defaultConfigs}, []config.Option{config.WithProfile("dev")}, "dev"},
+		{"env over profile", &config.FSProvider{FS: defaultConfigs, Env: "default"}, []config.Option{config.WithProfile("dev")}, "default"},
+		{"template", &config.FSProvider{FS: defaultConfigs, PathTemplate: "envs/{{env}}/app.toml", Env: "dev"}, nil, "templated"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, cfg := loadProvider(t, tc.provider, tc.opts...)
+			if cfg.Name != tc.want {
+				t.Fatalf("name = %q, want %q", cfg.Name, tc.want)
+			}
+		})
+	}
+}
+
+func TestFSProviderNotExist(t *testing.T) {
+	load := func(provider config.Provider) error {
+		return config.New(
+			config.WithProviders(provider),
+			config.WithFlagParser(func() config.FlagParseResult { return flagResult{} }),
+			config.WithLogger(&captureLogger{}),
+		).Load(&nameConfig{})
+	}
+	if err := load(&config.FSProvider{FS: defaultConfigs, Env: "prod"}); err == nil || !strings.Contains(err.Error(), "configs/prod.toml") {
+		t.Fatalf("want not exist error of configs/prod.toml, got %v", err)
+	}
+	if err := load(&config.FSProvider{FS: defaultConfigs, Env: "empty"}); !errors.Is(err, config.ErrEmptyConfig) {
+		t.Fatalf("want empty config error, got %v", err)
+	}
+	if err := load(&config.FSProvider{}); err == nil {
+		t.Fatal("want error of nil fs")
+	}
+}
+
+// the baked-in defaults are the lowest layer, the local file overrides them
+func TestFSProviderLowestLayer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "name = \"local\"\n")
+
+	cfg := &nameConfig{}
+	err := config.New(
+		config.WithProviders(&config.FSProvider{FS: defaultConfigs, Env: "dev"}, &config.FileProvider{}),
+		config.WithMergeProviders(true),
+		config.WithFlagParser(func() config.FlagParseResult { return flagResult{configFile: file} }),
+		config.WithLogger(&captureLogger{}),
+	).Load(cfg)
+	if err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if cfg.Name != "local" || cfg.Log.Level != "debug" {
+		t.Fatalf("name = %q level = %q", cfg.Name, cfg.Log.Level)
+	}
+
+	// a missing env file is skipped with SkipIfNotExist
+	cfg = &nameConfig{}
+	err = config.New(
+		config.WithProviders(&config.FSProvider{FS: defaultConfigs, Env: "prod", SkipIfNotExist: true}, &config.FileProvider{}),
+		config.WithMergeProviders(true),
+		config.WithFlagParser(func() config.FlagParseResult { return flagResult{configFile: file} }),
+		config.WithLogger(&captureLogger{}),
+	).Load(cfg)
+	if err != nil || cfg.Name != "local" {
+		t.Fatalf("load with skipped fs failed, name = %q err=%v", cfg.Name, err)
+	}
+}
+
+func TestFSProviderSignature(t *testing.T) {
+	s := newSigner(t)
+	content := []byte("name = \"signed\"\n")
+	sig, err := config.SignConfig(content, s.private, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"configs/default.toml":         {Data: content},
+		"configs/default.toml.minisig": {Data: sig},
+		"configs/dev.toml":             {Data: []byte("name = \"unsigned\"\n")},
+	}
+
+	_, cfg := loadProvider(t, &config.FSProvider{FS: fsys}, config.WithTrustedKeys(s.public))
+	if cfg.Name != "signed" {
+		t.Fatalf("name = %q", cfg.Name)
+	}
+	err = config.New(
+		config.WithProviders(&config.FSProvider{FS: fsys, Env: "dev"}),
+		config.WithFlagParser(func() config.FlagParseResult { return flagResult{} }),
+		config.WithLogger(&captureLogger{}),
+		config.WithTrustedKeys(s.public),
+	).Load(&nameConfig{})
+	if !errors.Is(err, config.ErrSignatureVerification) {
+		t.Fatalf("want signature error of unsigned file, got %v", err)
+	}
+}
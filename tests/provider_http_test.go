@@ -0,0 +1,258 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kk-kwok/config"
+)
+
+type nameConfig struct {
+	config.Base
+	Name string `toml:"name"`
+}
+
+// loadProvider load the config from the provider only
+func loadProvider(t *testing.T, provider config.Provider, opts ...config.Option) (*config.ConfigLoader, *nameConfig) {
+	t.Helper()
+	cfg := &nameConfig{}
+	opts = append([]config.Option{
+		config.WithProviders(provider),
+		config.WithFlagParser(func() config.FlagParseResult { return flagResult{} }),
+		config.WithLogger(&captureLogger{}),
+	}, opts...)
+	loader := config.New(opts...)
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	return loader, cfg
+}
+
+func TestHTTPProviderETag(t *testing.T) {
+	var mu sync.Mutex
+	body, etag := "name = \"v1\"\n", `"v1"`
+	var requests, notModified int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	loader, cfg := loadProvider(t, &config.HTTPProvider{URL: srv.URL})
+	if cfg.Name != "v1" {
+		t.Fatalf("name = %q", cfg.Name)
+	}
+	// the cached content is used on 304
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	if got := loader.Current().(*nameConfig).Name; got != "v1" || atomic.LoadInt32(&notModified) != 1 {
+		t.Fatalf("name = %q, not modified = %d", got, notModified)
+	}
+
+	mu.Lock()
+	body, etag = "name = \"v2\"\n", `"v2"`
+	mu.Unlock()
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	if got := loader.Current().(*nameConfig).Name; got != "v2" {
+		t.Fatalf("name = %q after change", got)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("requests = %d, want 3", n)
+	}
+}
+
+func TestHTTPProviderRetryBackoff(t *testing.T) {
+	var mu sync.Mutex
+	var attempts []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, time.Now())
+		if len(attempts) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("name = \"ok\"\n"))
+	}))
+	defer srv.Close()
+
+	backoff := 20 * time.Millisecond
+	_, cfg := loadProvider(t, &config.HTTPProvider{URL: srv.URL, Retries: 3, RetryBackoff: backoff})
+	if cfg.Name != "ok" {
+		t.Fatalf("name = %q", cfg.Name)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 3 {
+		t.Fatalf("attempts = %d, want 3", len(attempts))
+	}
+	// the backoff doubles
+	if d := attempts[1].Sub(attempts[0]); d < backoff {
+		t.Errorf("first backoff %v < %v", d, backoff)
+	}
+	if d := attempts[2].Sub(attempts[1]); d < 2*backoff {
+		t.Errorf("second backoff %v < %v", d, 2*backoff)
+	}
+}
+
+func TestHTTPProviderNoRetryOnClientError(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	loader := config.New(
+		config.WithProviders(&config.HTTPProvider{URL: srv.URL, RetryBackoff: time.Millisecond}),
+		config.WithFlagParser(func() config.FlagParseResult { return flagResult{} }),
+		config.WithLogger(&captureLogger{}),
+	)
+	if err := loader.Load(&nameConfig{}); err == nil {
+		t.Fatal("want error for 403")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("requests = %d, want 1", n)
+	}
+}
+
+func TestHTTPProviderSignature(t *testing.T) {
+	s := newSigner(t)
+	content := []byte("name = \"signed\"\n")
+	sig, err := config.SignConfig(content, s.private, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("env") != "prod" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Path {
+		case "/config.toml":
+			_, _ = w.Write(content)
+		case "/config.toml.sig":
+			_, _ = w.Write(sig)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	_, cfg := loadProvider(t, &config.HTTPProvider{URL: srv.URL + "/config.toml?env=prod"}, config.WithTrustedKeys(s.public))
+	if cfg.Name != "signed" {
+		t.Fatalf("name = %q", cfg.Name)
+	}
+}
+
+// the config is replaced between the content and the signature requests, the pair is fetched again
+func TestHTTPProviderSignatureRotation(t *testing.T) {
+	s := newSigner(t)
+	sign := func(content string) []byte {
+		sig, err := config.SignConfig([]byte(content), s.private, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+	v1, v2 := "name = \"v1\"\n", "name = \"v2\"\n"
+	var mu sync.Mutex
+	// the content and signature served for each request in order, the last one repeats
+	contents := []string{v2, v2, v2}
+	sigs := [][]byte{sign(v1), sign(v2)}
+	var sigRequests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/config.toml":
+			_, _ = w.Write([]byte(contents[0]))
+			if len(contents) > 1 {
+				contents = contents[1:]
+			}
+		case "/config.toml.sig":
+			atomic.AddInt32(&sigRequests, 1)
+			_, _ = w.Write(sigs[0])
+			if len(sigs) > 1 {
+				sigs = sigs[1:]
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	provider := &config.HTTPProvider{URL: srv.URL + "/config.toml", RetryBackoff: time.Millisecond}
+	loader, cfg := loadProvider(t, provider, config.WithTrustedKeys(s.public))
+	if cfg.Name != "v2" {
+		t.Fatalf("name = %q", cfg.Name)
+	}
+	// the signature is not fetched again for the verification by the loader
+	if n := atomic.LoadInt32(&sigRequests); n != 2 {
+		t.Fatalf("signature requests = %d, want 2", n)
+	}
+
+	// a signature that never matches fails the reload and keeps the config
+	mu.Lock()
+	contents = []string{v1}
+	sigs = [][]byte{sign(v2)}
+	mu.Unlock()
+	if err := loader.Reload(); err == nil {
+		t.Fatal("reload with mismatched signature succeeded")
+	}
+	if got := loader.Current().(*nameConfig).Name; got != "v2" {
+		t.Fatalf("name = %q after the failed reload", got)
+	}
+}
+
+func TestHTTPProviderPoll(t *testing.T) {
+	var mu sync.Mutex
+	body := "name = \"v1\"\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	changed := make(chan string, 1)
+	provider := &config.HTTPProvider{
+		URL:          srv.URL,
+		PollInterval: 10 * time.Millisecond,
+		ChangeListener: func(namespace, group, dataID, data string) {
+			select {
+			case changed <- data:
+			default:
+			}
+		},
+	}
+	loader, _ := loadProvider(t, provider)
+	defer loader.Close()
+
+	mu.Lock()
+	body = "name = \"v2\"\n"
+	mu.Unlock()
+	select {
+	case data := <-changed:
+		if data != "name = \"v2\"\n" {
+			t.Fatalf("changed data = %q", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not notified")
+	}
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kk-kwok/config"
+)
+
+func TestReloadOnChange(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "name = \"v1\"\n")
+
+	var notified int32
+	provider := &config.FileProvider{
+		WatchInterval: 20 * time.Millisecond,
+		ChangeListener: func(namespace, group, dataID, data string) {
+			atomic.AddInt32(&notified, 1)
+		},
+	}
+	cfg := &nameConfig{}
+	log := &captureLogger{}
+	loader := config.New(
+		config.WithProviders(provider),
+		config.WithFlagParser(func() config.FlagParseResult { return flagResult{configFile: file} }),
+		config.WithLogger(log),
+		config.WithReloadOnChange(true),
+	)
+	reloaded := make(chan string, 10)
+	loader.OnReload(func(event config.ReloadEvent) {
+		reloaded <- event.New.(*nameConfig).Name
+	})
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	defer loader.Close()
+
+	writeFile(t, file, "name = \"v2-changed\"\n")
+	select {
+	case name := <-reloaded:
+		if name != "v2-changed" {
+			t.Fatalf("reloaded name = %q", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("config not reloaded on file change, notified=%d log=%v", atomic.LoadInt32(&notified), log.lines)
+	}
+	if atomic.LoadInt32(&notified) == 0 {
+		t.Fatal("the ChangeListener of the provider not called")
+	}
+	if got := loader.Current().(*nameConfig).Name; got != "v2-changed" {
+		t.Fatalf("current name = %q", got)
+	}
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+type ServerConfig struct {
+	Port int    `toml:"port" reload:"restart"`
+	Name string `toml:"name"`
+}
+
+type policyConfig struct {
+	config.Base
+	ServerConfig
+	Database struct {
+		Driver string `toml:"driver" reload:"immutable"`
+		Pool   int    `toml:"pool"`
+		TLS    struct {
+			CA   string `toml:"ca"`
+			Cert string `toml:"cert"`
+		} `toml:"tls" reload:"restart"`
+	} `toml:"database"`
+}
+
+const policyBase = `
+port = 80
+name = "app"
+
+[database]
+driver = "mysql"
+pool = 10
+
+[database.tls]
+ca = "ca.pem"
+cert = "cert.pem"
+`
+
+func loadPolicy(t *testing.T, opts ...config.Option) (*config.ConfigLoader, string) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, policyBase)
+	loader := newFileLoader(file, opts...)
+	if err := loader.Load(&policyConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	return loader, file
+}
+
+func TestReloadImmutableRejected(t *testing.T) {
+	loader, file := loadPolicy(t)
+	notified := 0
+	loader.OnReload(func(event config.ReloadEvent) { notified++ })
+
+	writeFile(t, file, `
+port = 80
+name = "app-v2"
+
+[database]
+driver = "postgres"
+pool = 20
+
+[database.tls]
+ca = "ca.pem"
+cert = "cert.pem"
+`)
+	err := loader.Reload()
+	if !errors.Is(err, config.ErrImmutableChanged) {
+		t.Fatalf("reload err = %v, want ErrImmutableChanged", err)
+	}
+	cfg := loader.Current().(*policyConfig)
+	if cfg.Database.Driver != "mysql" || cfg.Database.Pool != 10 || cfg.Name != "app" {
+		t.Fatalf("the rejected reload applied, got %+v", cfg)
+	}
+	if notified != 0 {
+		t.Fatal("listeners notified of the rejected reload")
+	}
+}
+
+func TestReloadRestartReported(t *testing.T) {
+	var reported config.Changes
+	loader, file := loadPolicy(t, config.WithRestartRequired(func(changes config.Changes) {
+		reported = changes
+	}))
+	var event config.ReloadEvent
+	loader.OnReload(func(e config.ReloadEvent) { event = e })
+
+	writeFile(t, file, `
+port = 8080
+name = "app-v2"
+
+[database]
+driver = "mysql"
+pool = 20
+
+[database.tls]
+ca = "ca.pem"
+cert = "cert-v2.pem"
+`)
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	cfg := loader.Current().(*policyConfig)
+	// the hot fields are applied, the restart fields of the embedded and the nested struct keep the old values
+	if cfg.Name != "app-v2" || cfg.Database.Pool != 20 {
+		t.Fatalf("hot fields not applied, got %+v", cfg)
+	}
+	if cfg.Port != 80 || cfg.Database.TLS.Cert != "cert.pem" {
+		t.Fatalf("restart fields applied, got %+v", cfg)
+	}
+	if got := fmt.Sprint(reported.Paths()); got != "[database.tls.cert port]" {
+		t.Fatalf("reported restart changes = %v", got)
+	}
+	if got := fmt.Sprint(event.RestartRequired.Paths()); got != "[database.tls.cert port]" {
+		t.Fatalf("event restart changes = %v", got)
+	}
+	if !event.Changes.Under("database.pool") || !event.Changes.Under("name") {
+		t.Fatalf("event changes = %v", event.Changes.Strings())
+	}
+}
+
+type invalidPolicyConfig struct {
+	config.Base
+	Port int `toml:"port" reload:"sometimes"`
+}
+
+func TestInvalidReloadPolicy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "port = 80\n")
+	if err := newFileLoader(file).Load(&invalidPolicyConfig{}); err == nil {
+		t.Fatal("invalid reload tag not rejected")
+	}
+}
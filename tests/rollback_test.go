@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kk-kwok/config"
+)
+
+func TestReloadRollback(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "name = \"good\"\n")
+
+	loader := newFileLoader(file)
+	if err := loader.Load(&nameConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	var notified []string
+	loader.OnReload(func(event config.ReloadEvent) {
+		notified = append(notified, event.New.(*nameConfig).Name)
+	})
+	var checked []string
+	loader.AddReloadHealthCheck(func(ctx context.Context, event config.ReloadEvent) error {
+		// the new config is current while checking
+		if loader.Current() != event.New {
+			t.Error("the checked config is not current")
+		}
+		name := event.New.(*nameConfig).Name
+		checked = append(checked, name)
+		if name == "bad" {
+			return errors.New("db unreachable")
+		}
+		return nil
+	})
+
+	writeFile(t, file, "name = \"bad\"\n")
+	err := loader.Reload()
+	if !errors.Is(err, config.ErrReloadRolledBack) {
+		t.Fatalf("reload err = %v, want ErrReloadRolledBack", err)
+	}
+	if got := loader.Current().(*nameConfig).Name; got != "good" {
+		t.Fatalf("current name = %q after rollback", got)
+	}
+	if len(notified) != 0 {
+		t.Fatalf("listeners notified of the rolled back reload, got %v", notified)
+	}
+	if len(loader.History()) != 0 {
+		t.Fatalf("the rolled back config recorded in history, got %v", loader.History())
+	}
+
+	writeFile(t, file, "name = \"better\"\n")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	if got := loader.Current().(*nameConfig).Name; got != "better" {
+		t.Fatalf("current name = %q", got)
+	}
+	if fmt.Sprint(notified) != "[better]" || fmt.Sprint(checked) != "[bad better]" {
+		t.Fatalf("notified = %v checked = %v", notified, checked)
+	}
+}
+
+func TestReloadHealthCheckTimeout(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "name = \"v1\"\n")
+
+	loader := newFileLoader(file, config.WithReloadHealthCheckTimeout(20*time.Millisecond))
+	if err := loader.Load(&nameConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	loader.AddReloadHealthCheck(func(ctx context.Context, event config.ReloadEvent) error {
+		<-ctx.Done()
+		return nil
+	})
+	writeFile(t, file, "name = \"v2\"\n")
+	if err := loader.Reload(); !errors.Is(err, config.ErrReloadRolledBack) {
+		t.Fatalf("reload err = %v, want ErrReloadRolledBack", err)
+	}
+	if got := loader.Current().(*nameConfig).Name; got != "v1" {
+		t.Fatalf("current name = %q after timeout", got)
+	}
+}
+
+func TestSnapshotHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "name = \"v0\"\n")
+
+	loader := newFileLoader(file, config.WithSnapshotHistory(2))
+	if err := loader.Load(&nameConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	for i := 1; i <= 4; i++ {
+		writeFile(t, file, fmt.Sprintf("name = \"v%d\"\n", i))
+		if err := loader.Reload(); err != nil {
+			t.Fatalf("reload %d failed, err=%v", i, err)
+		}
+	}
+	history := loader.History()
+	if len(history) != 2 {
+		t.Fatalf("history has %d configs, want 2", len(history))
+	}
+	// newest first
+	for i, want := range []string{"v3", "v2"} {
+		if got := history[i].Config.(*nameConfig).Name; got != want {
+			t.Fatalf("history[%d] = %q, want %q", i, got, want)
+		}
+		if history[i].SHA256 == "" || history[i].LoadedAt.IsZero() {
+			t.Fatalf("history[%d] = %+v", i, history[i])
+		}
+	}
+
+	off := newFileLoader(file, config.WithSnapshotHistory(-1))
+	if err := off.Load(&nameConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if err := off.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	if len(off.History()) != 0 {
+		t.Fatalf("history kept with WithSnapshotHistory(-1), got %v", off.History())
+	}
+}
@@ -0,0 +1,218 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+type secretConfig struct {
+	config.Base
+	DSN      string   `toml:"dsn"`
+	Template string   `toml:"template"`
+	Password string   `toml:"db_ref" secret:"true"`
+	Tokens   []string `toml:"keys"`
+}
+
+func writeFile(t *testing.T, path, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileSecretResolverDir(t *testing.T) {
+	dir := t.TempDir()
+	secret := writeFile(t, filepath.Join(dir, "secrets", "db"), "topsecret\n")
+	writeFile(t, filepath.Join(dir, "outside"), "leaked")
+	r := &config.FileSecretResolver{Dir: filepath.Join(dir, "secrets")}
+
+	got, err := r.Resolve(context.Background(), "file://"+secret)
+	if err != nil || got != "topsecret" {
+		t.Fatalf("resolve %v = %q, %v", secret, got, err)
+	}
+	for _, ref := range []string{
+		"file://" + filepath.Join(dir, "secrets") + "/../outside",
+		"file://" + filepath.Join(dir, "secrets-other", "db"),
+		"file://" + filepath.Join(dir, "secrets"),
+	} {
+		if got, err := r.Resolve(context.Background(), ref); err == nil {
+			t.Errorf("resolve %v = %q, want error", ref, got)
+		}
+	}
+}
+
+func TestFileSecretResolverRelative(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "secrets", "db"), "topsecret\n")
+
+	r := &config.FileSecretResolver{Dir: dir}
+	got, err := r.Resolve(context.Background(), "file://secrets/db")
+	if err != nil || got != "topsecret" {
+		t.Fatalf("resolve relative to Dir = %q, %v", got, err)
+	}
+	if got, err := r.Resolve(context.Background(), "file://../outside"); err == nil {
+		t.Fatalf("resolve outside of Dir = %q, want error", got)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	got, err = (&config.FileSecretResolver{}).Resolve(context.Background(), "file://secrets/db")
+	if err != nil || got != "topsecret" {
+		t.Fatalf("resolve relative to the working directory = %q, %v", got, err)
+	}
+}
+
+func TestVaultSecretResolver(t *testing.T) {
+	secrets := map[string]string{
+		"/v1/kv/data/mysql": `{"data":{"data":{"password":"kv2-secret","user":"app"},"metadata":{"version":3}}}`,
+		"/v1/secret/redis":  `{"data":{"password":"kv1-secret"}}`,
+	}
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "root-token" || r.Header.Get("X-Vault-Namespace") != "team" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		body, ok := secrets[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	r := &config.VaultSecretResolver{Addr: server.URL, Token: "root-token", Namespace: "team"}
+	for ref, want := range map[string]string{
+		"vault://kv/data/mysql#password": "kv2-secret",
+		"vault://kv/data/mysql#user":     "app",
+		"vault://secret/redis#password":  "kv1-secret",
+		"vault://secret/redis":           "kv1-secret",
+	} {
+		got, err := r.Resolve(context.Background(), ref)
+		if err != nil || got != want {
+			t.Errorf("resolve %v = %q, %v, want %q", ref, got, err, want)
+		}
+	}
+	for _, ref := range []string{"vault://kv/data/mysql", "vault://kv/data/mysql#missing", "vault://kv/data/none#password", "vault://"} {
+		if got, err := r.Resolve(context.Background(), ref); err == nil {
+			t.Errorf("resolve %v = %q, want error", ref, got)
+		}
+	}
+	if _, err := (&config.VaultSecretResolver{Addr: server.URL}).Resolve(context.Background(), "vault://secret/redis"); err == nil {
+		t.Error("resolve without token succeeded")
+	}
+
+	// the same reference is resolved once per load
+	file := writeFile(t, filepath.Join(t.TempDir(), "config.toml"),
+		"dsn = \"vault://kv/data/mysql#password\"\ndb_ref = \"vault://kv/data/mysql#password\"\n")
+	requests = 0
+	cfg := &secretConfig{}
+	if err := newFileLoader(file, config.WithLogger(&captureLogger{}), config.WithSecretResolvers(r)).Load(cfg); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if cfg.DSN != "kv2-secret" || cfg.Password != "kv2-secret" || requests != 1 {
+		t.Fatalf("dsn=%q password=%q requests=%d", cfg.DSN, cfg.Password, requests)
+	}
+}
+
+func TestSecretResolveRedacted(t *testing.T) {
+	dir := t.TempDir()
+	dsn := writeFile(t, filepath.Join(dir, "dsn"), "opaque-topsecret")
+	password := writeFile(t, filepath.Join(dir, "password"), "hunter2")
+	token := writeFile(t, filepath.Join(dir, "token"), "tok-value")
+	// the secret: marker is optional, values of the schemes without a resolver are kept
+	content := "dsn = \"file://" + dsn + "\"\n" +
+		"template = \"https://example.com/template\"\n" +
+		"db_ref = \"file://" + password + "\"\n" +
+		"keys = [\"secret:file://" + token + "\"]\n"
+	file := writeFile(t, filepath.Join(dir, "config.toml"), content)
+
+	logger := &captureLogger{}
+	cfg := &secretConfig{}
+	loader := newFileLoader(file, config.WithLogger(logger),
+		config.WithSecretResolvers(&config.FileSecretResolver{Dir: dir}))
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	if cfg.DSN != "opaque-topsecret" || cfg.Password != "hunter2" || cfg.Tokens[0] != "tok-value" {
+		t.Fatalf("secrets not resolved, %+v", cfg)
+	}
+	if cfg.Template != "https://example.com/template" {
+		t.Fatalf("value without resolver changed, template=%v", cfg.Template)
+	}
+	for _, secret := range []string{"topsecret", "hunter2", "tok-value"} {
+		if logger.contains(secret) {
+			t.Errorf("resolved secret %q logged", secret)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	loader.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config?format=json", nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || strings.Contains(body, "topsecret") || strings.Contains(body, "tok-value") {
+		t.Fatalf("admin config not redacted, code=%v body=%s", rec.Code, body)
+	}
+
+	// the changed secret is redacted in the reload changes
+	writeFile(t, dsn, "opaque-changed")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	for _, change := range loader.ReloadStatus().LastChanges {
+		if strings.Contains(change.String(), "changed") || strings.Contains(change.String(), "topsecret") {
+			t.Errorf("change not redacted, %v", change)
+		}
+	}
+}
+
+func TestSecretRefWithoutResolver(t *testing.T) {
+	file := writeFile(t, filepath.Join(t.TempDir(), "config.toml"), "dsn = \"secret:vault://kv/data/mysql#dsn\"\n")
+	loader := newFileLoader(file, config.WithLogger(&captureLogger{}),
+		config.WithSecretResolvers(&config.FileSecretResolver{}))
+	if err := loader.Load(&secretConfig{}); err == nil {
+		t.Fatal("want error for secret reference without resolver")
+	}
+}
+
+func TestSecretNotDumped(t *testing.T) {
+	dir := t.TempDir()
+	dsn := writeFile(t, filepath.Join(dir, "dsn"), "opaque-topsecret")
+	file := writeFile(t, filepath.Join(dir, "config.toml"), "dsn = \"file://"+dsn+"\"\n")
+
+	for _, format := range []config.DumpFormat{config.DumpFormatTOML, config.DumpFormatJSON, config.DumpFormatEnv} {
+		cfg := &secretConfig{}
+		var err error
+		out := captureOutput(t, &os.Stderr, func() {
+			err = newFileLoader(file, config.WithLogger(&captureLogger{}), config.WithDumpMarshalledConfig(true),
+				config.WithDumpFormat(format), config.WithSecretResolvers(&config.FileSecretResolver{Dir: dir})).Load(cfg)
+		})
+		if err != nil {
+			t.Fatalf("load failed, err=%v", err)
+		}
+		if cfg.DSN != "opaque-topsecret" {
+			t.Fatalf("secret not resolved, dsn=%q", cfg.DSN)
+		}
+		if !strings.Contains(out, config.RedactedValue) || strings.Contains(out, "topsecret") {
+			t.Fatalf("resolved secret in %v dump:\n%s", format, out)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kk-kwok/config"
+)
+
+func sendSignal(t *testing.T, sig os.Signal) {
+	t.Helper()
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(sig); err != nil {
+		t.Fatalf("send %v failed, err=%v", sig, err)
+	}
+}
+
+func TestReloadOnSignal(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "name = \"v1\"\n")
+	log := &captureLogger{}
+	inspected := make(chan string, 10)
+	loader := newFileLoader(file,
+		config.WithLogger(log),
+		config.WithReloadOnSignal(),
+		config.WithInspectConfig(func(cfg interface{}) error {
+			inspected <- cfg.(*nameConfig).Name
+			return nil
+		}),
+	)
+	reloaded := make(chan config.ReloadEvent, 10)
+	loader.OnReload(func(event config.ReloadEvent) { reloaded <- event })
+	if err := loader.Load(&nameConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	defer loader.Close()
+	<-inspected
+
+	writeFile(t, file, "name = \"v2\"\n")
+	sendSignal(t, syscall.SIGHUP)
+	select {
+	case event := <-reloaded:
+		if event.New.(*nameConfig).Name != "v2" || !event.Changes.Under("name") {
+			t.Fatalf("reload event = %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("config not reloaded on SIGHUP, log=%v", log.lines)
+	}
+	if name := <-inspected; name != "v2" {
+		t.Fatalf("inspected %q on reload", name)
+	}
+	if !log.contains("reload config on signal received signal=hangup") || !log.contains("~ name: v1 -> v2") {
+		t.Fatalf("signal reload not logged with the changes, log=%v", log.lines)
+	}
+
+	// a failed reload keeps the config and is recorded
+	writeFile(t, file, "name = \n")
+	sendSignal(t, syscall.SIGHUP)
+	deadline := time.Now().Add(5 * time.Second)
+	for loader.ReloadStatus().Failures == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("failed reload on SIGHUP not recorded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := loader.Current().(*nameConfig).Name; got != "v2" {
+		t.Fatalf("name = %q after the failed reload", got)
+	}
+}
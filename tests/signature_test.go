@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+type signedConfig struct {
+	config.Base
+	Name   string `toml:"name"`
+	Common string `toml:"common"`
+}
+
+type signer struct {
+	t       *testing.T
+	public  string
+	private string
+}
+
+func newSigner(t *testing.T) *signer {
+	t.Helper()
+	public, private, err := config.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &signer{t: t, public: public, private: private}
+}
+
+// write the file with its signature
+func (s *signer) write(path, content string) string {
+	s.t.Helper()
+	writeFile(s.t, path, content)
+	sig, err := config.SignConfig([]byte(content), s.private, "test")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	writeFile(s.t, path+".minisig", string(sig))
+	return path
+}
+
+func loadSigned(file string, s *signer, opts ...config.Option) (*signedConfig, error) {
+	cfg := &signedConfig{}
+	opts = append(opts, config.WithLogger(&captureLogger{}), config.WithTrustedKeys(s.public))
+	return cfg, newFileLoader(file, opts...).Load(cfg)
+}
+
+func TestSignedInclude(t *testing.T) {
+	dir := t.TempDir()
+	s := newSigner(t)
+	s.write(filepath.Join(dir, "common.toml"), "common = \"shared\"\nname = \"common\"\n")
+	file := s.write(filepath.Join(dir, "config.toml"), "include = [\"common.toml\"]\nname = \"root\"\n")
+
+	cfg, err := loadSigned(file, s)
+	if err != nil {
+		t.Fatalf("load signed config with include failed, err=%v", err)
+	}
+	if cfg.Name != "root" || cfg.Common != "shared" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	// an unsigned include is rejected
+	writeFile(t, filepath.Join(dir, "common.toml"), "common = \"tampered\"\n")
+	if _, err := loadSigned(file, s); !errors.Is(err, config.ErrSignatureVerification) {
+		t.Fatalf("want signature error for tampered include, got %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "common.toml.minisig")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSigned(file, s); !errors.Is(err, config.ErrSignatureVerification) {
+		t.Fatalf("want signature error for unsigned include, got %v", err)
+	}
+}
+
+func TestSignedProfileOverlay(t *testing.T) {
+	dir := t.TempDir()
+	s := newSigner(t)
+	file := s.write(filepath.Join(dir, "config.toml"), "name = \"base\"\ncommon = \"base\"\n")
+	overlay := s.write(filepath.Join(dir, "config.prod.toml"), "name = \"prod\"\n")
+
+	cfg, err := loadSigned(file, s, config.WithProfile("prod"))
+	if err != nil {
+		t.Fatalf("load signed config with overlay failed, err=%v", err)
+	}
+	if cfg.Name != "prod" || cfg.Common != "base" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	writeFile(t, overlay, "name = \"tampered\"\n")
+	if _, err := loadSigned(file, s, config.WithProfile("prod")); !errors.Is(err, config.ErrSignatureVerification) {
+		t.Fatalf("want signature error for tampered overlay, got %v", err)
+	}
+}
+
+func TestSignedRootTampered(t *testing.T) {
+	dir := t.TempDir()
+	s := newSigner(t)
+	file := s.write(filepath.Join(dir, "config.toml"), "name = \"root\"\n")
+	writeFile(t, file, "name = \"tampered\"\n")
+	if _, err := loadSigned(file, s); !errors.Is(err, config.ErrSignatureVerification) {
+		t.Fatalf("want signature error for tampered config, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+type limitConfig struct {
+	QPS   int `toml:"qps"`
+	Burst int `toml:"burst"`
+}
+
+type subscribeConfig struct {
+	config.Base
+	Name      string      `toml:"name"`
+	RateLimit limitConfig `toml:"ratelimit"`
+}
+
+func TestOnChange(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "name = \"a\"\n[ratelimit]\nqps = 10\nburst = 20\n")
+	loader := newFileLoader(file)
+
+	var got []string
+	record := func(path string) config.ChangeHandler {
+		return func(old, new interface{}) {
+			got = append(got, fmt.Sprintf("%v: %v -> %v", path, old, new))
+		}
+	}
+	loader.OnChange("ratelimit", record("ratelimit"))
+	loader.OnChange("ratelimit.qps", record("ratelimit.qps"))
+	loader.OnChange("ratelimit.q", record("ratelimit.q"))
+	loader.OnChange("name", record("name"))
+	if err := loader.Load(&subscribeConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+
+	writeFile(t, file, "name = \"a\"\n[ratelimit]\nqps = 10\nburst = 30\n")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	// the change of ratelimit.burst is under ratelimit only, ratelimit.q is not a prefix of the key
+	if want := "[ratelimit: {10 20} -> {10 30}]"; fmt.Sprint(got) != want {
+		t.Fatalf("handlers called %v, want %v", got, want)
+	}
+
+	got = nil
+	writeFile(t, file, "name = \"b\"\n[ratelimit]\nqps = 15\nburst = 30\n")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	if want := "[ratelimit: {10 30} -> {15 30} ratelimit.qps: 10 -> 15 name: a -> b]"; fmt.Sprint(got) != want {
+		t.Fatalf("handlers called %v, want %v", got, want)
+	}
+
+	// a reload without changes calls no handler
+	got = nil
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("handlers called without changes, got %v", got)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "[ratelimit]\nqps = 10\n")
+	loader := newFileLoader(file)
+	if err := loader.Load(&subscribeConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+
+	var qps []int
+	if err := config.Subscribe(loader, "ratelimit.qps", func(old, new int) {
+		qps = append(qps, old, new)
+	}); err != nil {
+		t.Fatalf("subscribe failed, err=%v", err)
+	}
+	var limits []limitConfig
+	if err := config.Subscribe(loader, "ratelimit", func(old, new limitConfig) {
+		limits = append(limits, old, new)
+	}); err != nil {
+		t.Fatalf("subscribe failed, err=%v", err)
+	}
+	if err := config.Subscribe(loader, "ratelimit.qps", func(old, new string) {}); err == nil {
+		t.Fatal("subscribe with the wrong type not rejected")
+	}
+	if err := config.Subscribe(loader, "ratelimit.rps", func(old, new int) {}); err == nil {
+		t.Fatal("subscribe of unknown key not rejected")
+	}
+
+	writeFile(t, file, "[ratelimit]\nqps = 15\n")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	if fmt.Sprint(qps) != "[10 15]" {
+		t.Fatalf("qps handler got %v", qps)
+	}
+	if fmt.Sprint(limits) != "[{10 0} {15 0}]" {
+		t.Fatalf("ratelimit handler got %v", limits)
+	}
+}
+
+// the type is only checked on change when subscribed before Load
+func TestSubscribeTypeMismatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "[ratelimit]\nqps = 10\n")
+	log := &captureLogger{}
+	loader := newFileLoader(file, config.WithLogger(log))
+
+	called := false
+	if err := config.Subscribe(loader, "ratelimit.qps", func(old, new string) { called = true }); err != nil {
+		t.Fatalf("subscribe before load failed, err=%v", err)
+	}
+	if err := loader.Load(&subscribeConfig{}); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	writeFile(t, file, "[ratelimit]\nqps = 15\n")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	if called {
+		t.Fatal("handler called with the wrong type")
+	}
+	if !log.contains("config change not delivered, type mismatch path=ratelimit.qps") {
+		t.Fatalf("type mismatch not logged, log=%v", log.lines)
+	}
+}
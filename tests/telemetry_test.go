@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+func TestMetricsRegistryAfterReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "name = \"v1\"\nmetric = true\nmetric_go = true\n")
+
+	cfg := &nameConfig{}
+	loader := newFileLoader(file, config.WithLogger(&captureLogger{}))
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	shutdown, err := cfg.Setup(context.Background())
+	if err != nil {
+		t.Fatalf("setup failed, err=%v", err)
+	}
+	defer func() { _ = shutdown(context.Background()) }()
+	registry := cfg.MetricsRegistry()
+	if registry == nil {
+		t.Fatal("nil registry after setup")
+	}
+
+	writeFile(t, file, "name = \"v2\"\nmetric = true\nmetric_go = true\n")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	reloaded := loader.Current().(*nameConfig)
+	if reloaded.Name != "v2" {
+		t.Fatalf("name = %q after reload", reloaded.Name)
+	}
+	if reloaded.MetricsRegistry() != registry {
+		t.Fatal("registry of Setup lost after reload")
+	}
+	families, err := reloaded.MetricsRegistry().Gather()
+	if err != nil || len(families) == 0 {
+		t.Fatalf("gather reloaded registry failed, families=%d err=%v", len(families), err)
+	}
+}
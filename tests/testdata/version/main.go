@@ -0,0 +1,12 @@
+// print the version info, built by TestVersionLdflags
+package main
+
+import (
+	"fmt"
+
+	"github.com/kk-kwok/config/version"
+)
+
+func main() {
+	fmt.Println(version.Info())
+}
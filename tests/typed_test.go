@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kk-kwok/config"
+)
+
+type typedConfig struct {
+	config.Base
+	Name string `toml:"name"`
+	Port int    `toml:"port"`
+}
+
+func (c *typedConfig) SetDefaults() {
+	c.Port = 8080
+}
+
+func TestTypedLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, file, "name = \"v1\"\n")
+
+	cfg, h, err := config.Load[typedConfig](
+		config.WithProviders(&config.FileProvider{}),
+		config.WithFlagParser(func() config.FlagParseResult { return flagResult{configFile: file} }),
+	)
+	if err != nil {
+		t.Fatalf("load failed, err=%v", err)
+	}
+	defer h.Close()
+	if cfg.Name != "v1" || cfg.Port != 8080 {
+		t.Fatalf("config = %+v, want the defaults filled", cfg)
+	}
+	if h.Get() != cfg || h.Loader().Current() != cfg {
+		t.Fatal("the handle returns another config")
+	}
+
+	var reloaded []string
+	h.OnReload(func(old, new *typedConfig) {
+		reloaded = append(reloaded, old.Name, new.Name)
+	})
+	var changed []interface{}
+	h.OnChange("name", func(old, new interface{}) {
+		changed = append(changed, old, new)
+	})
+	var ports []int
+	if err := config.Subscribe(h.Loader(), "port", func(old, new int) { ports = append(ports, old, new) }); err != nil {
+		t.Fatalf("subscribe failed, err=%v", err)
+	}
+
+	writeFile(t, file, "name = \"v2\"\nport = 9090\n")
+	if err := h.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	if got := h.Get(); got.Name != "v2" || got.Port != 9090 {
+		t.Fatalf("reloaded config = %+v", got)
+	}
+	if len(reloaded) != 2 || reloaded[0] != "v1" || reloaded[1] != "v2" {
+		t.Fatalf("OnReload got %v", reloaded)
+	}
+	if len(changed) != 2 || changed[0] != "v1" || changed[1] != "v2" {
+		t.Fatalf("OnChange got %v", changed)
+	}
+	if len(ports) != 2 || ports[0] != 8080 || ports[1] != 9090 {
+		t.Fatalf("Subscribe got %v", ports)
+	}
+	// the defaults are filled before each reload too
+	writeFile(t, file, "name = \"v3\"\n")
+	if err := h.Reload(); err != nil {
+		t.Fatalf("reload failed, err=%v", err)
+	}
+	if got := h.Get(); got.Port != 8080 {
+		t.Fatalf("reloaded port = %v, want the default", got.Port)
+	}
+}
+
+func TestTypedLoadError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "missing.toml")
+	opts := []config.Option{
+		config.WithProviders(&config.FileProvider{}),
+		config.WithFlagParser(func() config.FlagParseResult { return flagResult{configFile: file} }),
+	}
+	cfg, h, err := config.Load[typedConfig](opts...)
+	if err == nil || cfg != nil || h != nil {
+		t.Fatalf("load of missing file = %v %v %v", cfg, h, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustLoad not panic")
+		}
+	}()
+	config.MustLoad[typedConfig](opts...)
+}
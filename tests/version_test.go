@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/kk-kwok/config/version"
+)
+
+func setVersion(t *testing.T, v, revision, branch, dirty string) {
+	t.Helper()
+	saved := []string{version.Version, version.Revision, version.Branch, version.Dirty}
+	t.Cleanup(func() {
+		version.Version, version.Revision, version.Branch, version.Dirty = saved[0], saved[1], saved[2], saved[3]
+	})
+	version.Version, version.Revision, version.Branch, version.Dirty = v, revision, branch, dirty
+}
+
+func TestVersionBuildInfoFallback(t *testing.T) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		t.Skip("no build info")
+	}
+	if version.Module == "" || version.Module != info.Main.Path {
+		t.Fatalf("Module = %q, want %q", version.Module, info.Main.Path)
+	}
+	if version.Version != info.Main.Version {
+		t.Fatalf("Version = %q, want %q", version.Version, info.Main.Version)
+	}
+}
+
+func TestVersionPrint(t *testing.T) {
+	setVersion(t, "v1.2.3", "abc123", "main", "true")
+	want := "(version=v1.2.3, branch=main, revision=abc123, dirty=true, module=" + version.Module + ")"
+	if got := version.Info(); got != want {
+		t.Fatalf("Info() = %q, want %q", got, want)
+	}
+
+	out := version.Print("demo")
+	for _, line := range []string{
+		"demo, version v1.2.3 (branch: main, revision: abc123, dirty: true)",
+		"module:           " + version.Module,
+		"go version:       " + runtime.Version(),
+		"platform:         " + runtime.GOOS + "/" + runtime.GOARCH,
+	} {
+		if !strings.Contains(out, line) {
+			t.Fatalf("Print() missing %q:\n%s", line, out)
+		}
+	}
+}
+
+func TestVersionLdflags(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	bin := filepath.Join(t.TempDir(), "version")
+	ldflags := "-X github.com/kk-kwok/config/version.Version=v9.9.9 -X github.com/kk-kwok/config/version.Revision=fromldflags"
+	build := exec.Command("go", "build", "-buildvcs=false", "-ldflags", ldflags, "-o", bin, "./testdata/version")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build failed, err=%v\n%s", err, out)
+	}
+	out, err := exec.Command(bin).Output()
+	if err != nil {
+		t.Fatalf("run failed, err=%v", err)
+	}
+	// -ldflags take precedence over the build info, the module is still filled from it
+	want := "(version=v9.9.9, branch=, revision=fromldflags, dirty=, module=github.com/kk-kwok/config)"
+	if got := strings.TrimSpace(string(out)); got != want {
+		t.Fatalf("version info = %q, want %q", got, want)
+	}
+}
@@ -13,6 +13,10 @@ func TomlUnmarshaler(p []byte, v interface{}) error {
 	return decodeErr(err)
 }
 
+func TomlMarshaler(v interface{}) ([]byte, error) {
+	return tomlv2.Marshal(v)
+}
+
 func TomlMarshalIndent(cfg interface{}) (string, error) {
 	buf := bytes.Buffer{}
 	enc := tomlv2.NewEncoder(&buf)
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"os"
+)
+
+// Defaulter is an optional interface of the config struct for the generic Load, SetDefaults is called on the
+// zero value to fill the default values before reading the providers
+type Defaulter interface {
+	SetDefaults()
+}
+
+// BaseConfig constrains PT to a pointer to the config struct T embedding Base, the unexported method of
+// profileSetter can only be promoted from Base
+type BaseConfig[T any] interface {
+	*T
+	BaseConfigEmbedded
+	profileSetter
+}
+
+// Handle is the typed view of the config loaded by Load, Get returns the current snapshot
+type Handle[T any] struct {
+	loader *ConfigLoader
+}
+
+// Get returns the current config, it is replaced on Reload and must not be modified
+func (h *Handle[T]) Get() *T {
+	return h.loader.Current().(*T)
+}
+
+// Reload see ConfigLoader.Reload
+func (h *Handle[T]) Reload() error {
+	return h.loader.Reload()
+}
+
+// OnReload register a listener called with the old and new config after each successful Reload
+func (h *Handle[T]) OnReload(listener func(old, new *T)) {
+	h.loader.OnReload(func(event ReloadEvent) {
+		listener(event.Old.(*T), event.New.(*T))
+	})
+}
+
+// OnChange see ConfigLoader.OnChange, use Subscribe(h.Loader(), ...) for typed values
+func (h *Handle[T]) OnChange(path string, handler ChangeHandler) {
+	h.loader.OnChange(path, handler)
+}
+
+// Close stop the watching of the providers, see ConfigLoader.Close
+func (h *Handle[T]) Close() {
+	h.loader.Close()
+}
+
+// Loader returns the underlying loader
+func (h *Handle[T]) Loader() *ConfigLoader {
+	return h.loader
+}
+
+// Load load the config struct T, which must embed Base, and returns the handle for reloading and closing, e.g.
+//
+//	cfg, h, err := config.Load[AppConfig](config.WithProviders(...))
+//	defer h.Close()
+func Load[T any, PT BaseConfig[T]](opts ...Option) (*T, *Handle[T], error) {
+	cfg := PT(new(T))
+	if d, ok := any(cfg).(Defaulter); ok {
+		d.SetDefaults()
+	}
+	cl := New(opts...)
+	if err := cl.Load(cfg); err != nil {
+		return nil, nil, err
+	}
+	h := &Handle[T]{loader: cl}
+	return h.Get(), h, nil
+}
+
+// MustLoad is like Load but panics on error, it exits 0 after the config is dumped by --dump
+func MustLoad[T any, PT BaseConfig[T]](opts ...Option) (*T, *Handle[T]) {
+	cfg, h, err := Load[T, PT](opts...)
+	if errors.Is(err, ErrConfigDumped) {
+		os.Exit(0)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return cfg, h
+}
@@ -1,11 +1,9 @@
 package version
 
-var initialFields = map[string]interface{}{
-	"service": ServiceName,
-	"version": Version,
-}
-
-// InitialFields for zap InitialFields
+// InitialFields for zap InitialFields, built on call so the build info fallback and overrides are included
 func InitialFields() map[string]interface{} {
-	return initialFields
+	return map[string]interface{}{
+		"service": ServiceName,
+		"version": Version,
+	}
 }
@@ -4,6 +4,7 @@ import (
     "bytes"
     "fmt"
     "runtime"
+    "runtime/debug"
     "strings"
     "text/template"
 
@@ -11,6 +12,8 @@ import (
 )
 
 // Build information. Populated at build-time.
+// Version, Revision, BuildDate, Module and Dirty fall back to debug.ReadBuildInfo if not set via -ldflags,
+// e.g. built by go install or go run in a vcs checkout.
 var (
     Version   string
     Revision  string
@@ -18,8 +21,35 @@ var (
     BuildUser string
     BuildDate string
     GoVersion = runtime.Version()
+    Module    string // the main module path
+    Dirty     string // "true" if built with uncommitted changes
 )
 
+func init() {
+    info, ok := debug.ReadBuildInfo()
+    if !ok {
+        return
+    }
+    setDefault(&Version, info.Main.Version)
+    setDefault(&Module, info.Main.Path)
+    for _, s := range info.Settings {
+        switch s.Key {
+        case "vcs.revision":
+            setDefault(&Revision, s.Value)
+        case "vcs.time":
+            setDefault(&BuildDate, s.Value)
+        case "vcs.modified":
+            setDefault(&Dirty, s.Value)
+        }
+    }
+}
+
+func setDefault(v *string, value string) {
+    if *v == "" {
+        *v = value
+    }
+}
+
 // NewCollector returns a collector that exports metrics about current version
 // information.
 func NewCollector(program string) prometheus.Collector {
@@ -28,13 +58,15 @@ func NewCollector(program string) prometheus.Collector {
             Namespace: program,
             Name:      "build_info",
             Help: fmt.Sprintf(
-                "A metric with a constant '1' value labeled by version, revision, branch, and goversion from which %s was built.",
+                "A metric with a constant '1' value labeled by version, revision, branch, dirty, module, and goversion from which %s was built.",
                 program,
             ),
             ConstLabels: prometheus.Labels{
                 "version":   Version,
                 "revision":  Revision,
                 "branch":    Branch,
+                "dirty":     Dirty,
+                "module":    Module,
                 "goversion": GoVersion,
             },
         },
@@ -44,7 +76,8 @@ func NewCollector(program string) prometheus.Collector {
 
 // versionInfoTmpl contains the template used by Info.
 var versionInfoTmpl = `
-{{.program}}, version {{.version}} (branch: {{.branch}}, revision: {{.revision}})
+{{.program}}, version {{.version}} (branch: {{.branch}}, revision: {{.revision}}, dirty: {{.dirty}})
+  module:           {{.module}}
   build user:       {{.buildUser}}
   build date:       {{.buildDate}}
   go version:       {{.goVersion}}
@@ -58,6 +91,8 @@ func Print(program string) string {
         "version":   Version,
         "revision":  Revision,
         "branch":    Branch,
+        "dirty":     Dirty,
+        "module":    Module,
         "buildUser": BuildUser,
         "buildDate": BuildDate,
         "goVersion": GoVersion,
@@ -72,9 +107,9 @@ func Print(program string) string {
     return strings.TrimSpace(buf.String())
 }
 
-// Info returns version, branch and revision information.
+// Info returns version, branch, revision, dirty and module information.
 func Info() string {
-    return fmt.Sprintf("(version=%s, branch=%s, revision=%s)", Version, Branch, Revision)
+    return fmt.Sprintf("(version=%s, branch=%s, revision=%s, dirty=%s, module=%s)", Version, Branch, Revision, Dirty, Module)
 }
 
 // BuildContext returns goVersion, buildUser and buildDate information.
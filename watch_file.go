@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultFileWatchInterval is the polling interval of file watching
+const DefaultFileWatchInterval = 5 * time.Second
+
+// filePoller poll the stat of the watched files, fsnotify is not used since
+// configmap mounts replace files via symlink swapping which is easy to miss
+type filePoller struct {
+	once sync.Once
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+type fileStat struct {
+	size    int64
+	modTime time.Time
+}
+
+// start polling once, files returns the current file list, onChange is called when any file is added, removed or modified
+func (w *filePoller) start(interval time.Duration, files func() []string, onChange func()) {
+	w.once.Do(func() {
+		if interval <= 0 {
+			interval = DefaultFileWatchInterval
+		}
+		w.mu.Lock()
+		w.stop = make(chan struct{})
+		stop := w.stop
+		w.mu.Unlock()
+		// stat before returning, a change right after the read is missed if the goroutine takes the first stat
+		last := statFiles(files())
+		go w.poll(interval, stop, last, files, onChange)
+	})
+}
+
+func (w *filePoller) poll(interval time.Duration, stop chan struct{}, last map[string]fileStat, files func() []string, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		current := statFiles(files())
+		if !sameStats(last, current) {
+			last = current
+			onChange()
+		}
+	}
+}
+
+func (w *filePoller) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+}
+
+func statFiles(files []string) map[string]fileStat {
+	stats := make(map[string]fileStat, len(files))
+	for _, f := range files {
+		// follow symlinks, the target changes when a configmap is updated
+		if info, err := os.Stat(f); err == nil {
+			stats[f] = fileStat{size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	return stats
+}
+
+func sameStats(a, b map[string]fileStat) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w.size != v.size || !w.modTime.Equal(v.modTime) {
+			return false
+		}
+	}
+	return true
+}